@@ -0,0 +1,129 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/blainemoser/MySqlMigrate/migrate"
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	ENV_DSN    = "MYSQLMIGRATE_DSN"
+	ENV_PATH   = "MYSQLMIGRATE_PATH"
+	ENV_SCHEMA = "MYSQLMIGRATE_SCHEMA"
+	ENV_TABLE  = "MYSQLMIGRATE_TABLE"
+
+	ENV_APP_VERSION   = "MYSQLMIGRATE_APP_VERSION"
+	ENV_MISSING_FILES = "MYSQLMIGRATE_MISSING_FILES"
+
+	DEFAULT_PATH = "migrations"
+)
+
+type config struct {
+	dsn    string
+	path   string
+	schema string
+	table  string
+	steps  int
+	dryRun bool
+
+	appVersion   string
+	missingFiles string
+
+	lockTimeout  time.Duration
+	lockFailFast bool
+	strict       bool
+
+	yes         bool
+	interactive bool
+
+	closers []func() // close the connection pools opened for the command
+}
+
+// register adds the flags for the command to its flag set. Values given on the
+// command line take precedence over the environment.
+func (c *config) register(flags *flag.FlagSet) {
+	flags.StringVar(&c.dsn, "dsn", os.Getenv(ENV_DSN), "MySQL DSN, eg user:secret@tcp(127.0.0.1:3306)/schema (env "+ENV_DSN+")")
+	flags.StringVar(&c.path, "path", envOr(ENV_PATH, DEFAULT_PATH), "directory containing the migration files (env "+ENV_PATH+")")
+	flags.StringVar(&c.schema, "schema", os.Getenv(ENV_SCHEMA), "schema to migrate; overrides the schema in the DSN (env "+ENV_SCHEMA+")")
+	flags.StringVar(&c.table, "table", envOr(ENV_TABLE, migrate.DEFAULT_TABLE), "table that records the migrations (env "+ENV_TABLE+")")
+	switch flags.Name() {
+	case "up", "down":
+		flags.IntVar(&c.steps, "steps", 0, "run or reverse at most this many migrations; 0 means no limit")
+		fallthrough
+	case "to":
+		flags.BoolVar(&c.dryRun, "dry-run", false, "print the SQL that would be executed without running it")
+		flags.StringVar(&c.appVersion, "app-version", os.Getenv(ENV_APP_VERSION), "version of the application to record with each migration (env "+ENV_APP_VERSION+")")
+	}
+	switch flags.Name() {
+	case "up", "down", "to", "repair":
+		flags.StringVar(&c.missingFiles, "missing-files", envOr(ENV_MISSING_FILES, string(migrate.MISSING_FILES_WARN)), "what to do with the record of a migration whose file is missing: warn, error, archive or delete (env "+ENV_MISSING_FILES+")")
+	}
+	switch flags.Name() {
+	case "up", "to":
+		flags.BoolVar(&c.strict, "strict", false, "refuse to run while applied migrations have changed")
+	}
+	if flags.Name() == "repair" {
+		flags.BoolVar(&c.yes, "yes", false, "fix every inconsistency that can be fixed without asking")
+		flags.BoolVar(&c.interactive, "interactive", false, "ask before fixing each inconsistency, and how to settle a dirty migration")
+	}
+	switch flags.Name() {
+	case "create", "up", "down", "to", "repair":
+		flags.DurationVar(&c.lockTimeout, "lock-timeout", migrate.DEFAULT_LOCK_TIMEOUT, "how long to wait for another process that is running migrations")
+		flags.BoolVar(&c.lockFailFast, "lock-fail-fast", false, "fail straight away if another process is running migrations")
+	}
+}
+
+// dataSource returns the DSN with the schema given by -schema, keeping every
+// other parameter of the DSN, such as tls, charset, parseTime and the timeouts
+func (c *config) dataSource() (string, error) {
+	if len(c.dsn) < 1 {
+		return "", fmt.Errorf("no DSN given; use -dsn or set %s", ENV_DSN)
+	}
+	parsed, err := mysql.ParseDSN(c.dsn)
+	if err != nil {
+		return "", fmt.Errorf("invalid DSN: %s", err.Error())
+	}
+	if len(c.schema) > 0 {
+		parsed.DBName = c.schema
+	}
+	if len(parsed.DBName) < 1 {
+		return "", errors.New("no schema given; add it to the DSN or use -schema")
+	}
+	return parsed.FormatDSN(), nil
+}
+
+// connection opens the connection pool to the schema that the migrations are
+// run, recorded, locked and wrapped in transactions through
+func (c *config) connection() (*sql.DB, error) {
+	dsn, err := c.dataSource()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, err
+	}
+	c.closers = append(c.closers, func() { conn.Close() })
+	return conn, nil
+}
+
+// close closes the connection pools opened for the command, newest first
+func (c *config) close() {
+	for i := len(c.closers) - 1; i >= 0; i-- {
+		c.closers[i]()
+	}
+	c.closers = nil
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); len(value) > 0 {
+		return value
+	}
+	return fallback
+}
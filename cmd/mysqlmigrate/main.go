@@ -0,0 +1,509 @@
+// Command mysqlmigrate creates, runs and reverses MySqlMigrate migrations.
+//
+// Usage:
+//
+//	mysqlmigrate <command> [flags] [arguments]
+//
+// Each outcome maps to a stable exit code (see the EXIT_* constants) so that
+// CI pipelines and init containers can act on the result.
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/blainemoser/MySqlMigrate/migrate"
+)
+
+const (
+	EXIT_OK         = 0 // the command completed successfully
+	EXIT_FAILURE    = 1 // an unexpected error occurred
+	EXIT_USAGE      = 2 // the command or its flags were not understood
+	EXIT_CONFIG     = 3 // the DSN, schema or path is missing or invalid
+	EXIT_CONNECTION = 4 // the database could not be reached
+	EXIT_MIGRATION  = 5 // a migration failed to run or reverse
+	EXIT_INVALID    = 6 // validation found problems with the migration files
+	EXIT_LOCKED     = 7 // another process held the migration lock
+	EXIT_DRIFT      = 8 // migrations that have been run have changed since
+	EXIT_DIRTY      = 9 // a migration failed part of the way through; see resume and force
+
+	EXIT_INCONSISTENT = 10 // the migrations table disagrees with the migration files; see repair
+
+	USAGE = `usage: mysqlmigrate <command> [flags] [arguments]
+
+commands:
+  create <name>      create a new migration file
+  up                 run all pending migrations, or -steps of them
+  down               reverse the last batch of migrations, or the last -steps migrations
+  to <target>        run or reverse migrations to land on the target name or id
+  status             list the migrations and whether they have run
+  validate           check the migration files without connecting
+  verify             list migrations that have changed since they were run
+  squash <target>    replace the migrations up to the target with a single baseline
+  baseline <target>  record the migrations up to the target as run, without running them
+  resume             finish the migration that failed part of the way through
+  force <target> <migrated|pending>
+                     record the target as run or not run, and clear its failed state
+  repair             report where the migrations table disagrees with the migration files,
+                     and fix it with -yes or -interactive
+
+run 'mysqlmigrate <command> -h' for the flags of a command
+`
+)
+
+type (
+	// exitError carries the exit code for the outcome of a command
+	exitError struct {
+		code int
+		err  error
+	}
+	command func(c *config, args []string, stdout io.Writer) error
+)
+
+var commands = map[string]command{
+	"create":   runCreate,
+	"up":       runUp,
+	"down":     runDown,
+	"to":       runTo,
+	"status":   runStatus,
+	"validate": runValidate,
+	"verify":   runVerify,
+	"squash":   runSquash,
+	"baseline": runBaseline,
+	"resume":   runResume,
+	"force":    runForce,
+	"repair":   runRepair,
+}
+
+// stdin is read for the answers to the questions asked by repair -interactive
+var stdin io.Reader = os.Stdin
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) < 1 {
+		fmt.Fprint(stderr, USAGE)
+		return EXIT_USAGE
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command '%s'\n\n%s", args[0], USAGE)
+		return EXIT_USAGE
+	}
+	c := &config{}
+	defer c.close()
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	c.register(flags)
+	if err := flags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return EXIT_OK
+		}
+		return EXIT_USAGE
+	}
+	if err := cmd(c, flags.Args(), stdout); err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return exitCode(err)
+	}
+	return EXIT_OK
+}
+
+func runCreate(c *config, args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return usageErr("create expects exactly one argument, the name of the migration")
+	}
+	m, err := c.migration()
+	if err != nil {
+		return err
+	}
+	fullPath, _, _, err := m.Create(args[0])
+	if err != nil {
+		return &exitError{EXIT_FAILURE, err}
+	}
+	fmt.Fprintf(stdout, "Created %s\n", fullPath)
+	return nil
+}
+
+func runUp(c *config, args []string, stdout io.Writer) error {
+	if c.steps < 0 {
+		return usageErr("-steps cannot be negative")
+	}
+	m, err := c.migration()
+	if err != nil {
+		return err
+	}
+	var result migrate.Result
+	if c.steps > 0 {
+		result, err = m.MigrateUpSteps(c.steps)
+	} else {
+		result, err = m.MigrateUp()
+	}
+	if err != nil {
+		return &exitError{EXIT_MIGRATION, err}
+	}
+	c.report(m, result, stdout)
+	return nil
+}
+
+func runDown(c *config, args []string, stdout io.Writer) error {
+	if c.steps < 0 {
+		return usageErr("-steps cannot be negative")
+	}
+	m, err := c.migration()
+	if err != nil {
+		return err
+	}
+	var result migrate.Result
+	if c.steps > 0 {
+		result, err = m.MigrateDownSteps(c.steps)
+	} else {
+		result, err = m.MigrateDown()
+	}
+	if err != nil {
+		return &exitError{EXIT_MIGRATION, err}
+	}
+	c.report(m, result, stdout)
+	return nil
+}
+
+func runTo(c *config, args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return usageErr("to expects exactly one argument, the name or id of the target migration")
+	}
+	m, err := c.migration()
+	if err != nil {
+		return err
+	}
+	result, err := m.MigrateTo(args[0])
+	if err != nil {
+		return &exitError{EXIT_MIGRATION, err}
+	}
+	c.report(m, result, stdout)
+	return nil
+}
+
+func runSquash(c *config, args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return usageErr("squash expects exactly one argument, the name or id of the last migration to squash")
+	}
+	m, err := c.migration()
+	if err != nil {
+		return err
+	}
+	message, err := m.Squash(args[0])
+	if err != nil {
+		return &exitError{EXIT_FAILURE, err}
+	}
+	fmt.Fprintln(stdout, message)
+	return nil
+}
+
+func runBaseline(c *config, args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return usageErr("baseline expects exactly one argument, the name or id of the last migration to record")
+	}
+	m, err := c.migration()
+	if err != nil {
+		return err
+	}
+	message, err := m.Baseline(args[0])
+	if err != nil {
+		return &exitError{EXIT_FAILURE, err}
+	}
+	fmt.Fprintln(stdout, message)
+	return nil
+}
+
+func runResume(c *config, args []string, stdout io.Writer) error {
+	if len(args) > 0 {
+		return usageErr("resume does not take any arguments")
+	}
+	m, err := c.migration()
+	if err != nil {
+		return err
+	}
+	result, err := m.Resume()
+	if err != nil {
+		return &exitError{EXIT_MIGRATION, err}
+	}
+	fmt.Fprintln(stdout, result.String())
+	return nil
+}
+
+func runForce(c *config, args []string, stdout io.Writer) error {
+	if len(args) != 2 || (args[1] != "migrated" && args[1] != "pending") {
+		return usageErr("force expects exactly two arguments, the name or id of the migration and its state, migrated or pending")
+	}
+	m, err := c.migration()
+	if err != nil {
+		return err
+	}
+	message, err := m.Force(args[0], args[1] == "migrated")
+	if err != nil {
+		return &exitError{EXIT_FAILURE, err}
+	}
+	fmt.Fprintln(stdout, message)
+	return nil
+}
+
+func runRepair(c *config, args []string, stdout io.Writer) error {
+	if len(args) > 0 {
+		return usageErr("repair does not take any arguments")
+	}
+	if c.yes && c.interactive {
+		return usageErr("-yes and -interactive cannot be used together")
+	}
+	m, err := c.migration()
+	if err != nil {
+		return err
+	}
+	input := bufio.NewReader(stdin)
+	var confirm func(migrate.Inconsistency) bool
+	switch {
+	case c.yes:
+		confirm = func(migrate.Inconsistency) bool { return true }
+	case c.interactive:
+		confirm = func(inconsistency migrate.Inconsistency) bool {
+			answer := ask(input, stdout, inconsistency.String()+"\nFix it? [y/N] ")
+			return answer == "y" || answer == "yes"
+		}
+	}
+	found, err := m.Repair(confirm)
+	if err != nil {
+		return &exitError{EXIT_FAILURE, err}
+	}
+	if len(found) < 1 {
+		fmt.Fprintln(stdout, "The migrations table agrees with the migration files")
+		return nil
+	}
+	left := false
+	for _, inconsistency := range found {
+		if inconsistency.Fixed {
+			fmt.Fprintf(stdout, "Fixed %s\n", inconsistency.String())
+			continue
+		}
+		if inconsistency.Kind == migrate.INCONSISTENCY_DIRTY && c.interactive {
+			settled, err := settleDirty(m, input, stdout, inconsistency)
+			if err != nil {
+				return err
+			}
+			if settled {
+				continue
+			}
+		}
+		fmt.Fprintln(stdout, inconsistency.String())
+		left = true
+	}
+	if left {
+		return &exitError{EXIT_INCONSISTENT, errors.New("the migrations table disagrees with the migration files; fix it with repair -yes or -interactive")}
+	}
+	return nil
+}
+
+// settleDirty asks whether to resume the dirty migration or force its state
+func settleDirty(m *migrate.Migration, input *bufio.Reader, stdout io.Writer, inconsistency migrate.Inconsistency) (bool, error) {
+	switch answer := ask(input, stdout, inconsistency.String()+"\n[r]esume it, force it to be [m]igrated or [p]ending, or [s]kip? "); answer {
+	case "r":
+		result, err := m.Resume()
+		if err != nil {
+			return false, &exitError{EXIT_MIGRATION, err}
+		}
+		fmt.Fprintln(stdout, result.String())
+		return true, nil
+	case "m", "p":
+		message, err := m.Force(inconsistency.Name, answer == "m")
+		if err != nil {
+			return false, &exitError{EXIT_FAILURE, err}
+		}
+		fmt.Fprintln(stdout, message)
+		return true, nil
+	}
+	return false, nil
+}
+
+// ask prints the question and reads the answer from a line of input
+func ask(input *bufio.Reader, stdout io.Writer, question string) string {
+	fmt.Fprint(stdout, question)
+	answer, _ := input.ReadString('\n')
+	return strings.ToLower(strings.TrimSpace(answer))
+}
+
+func runStatus(c *config, args []string, stdout io.Writer) error {
+	m, err := c.migration()
+	if err != nil {
+		return err
+	}
+	statuses, err := m.Status()
+	if err != nil {
+		return &exitError{EXIT_FAILURE, err}
+	}
+	if len(statuses) < 1 {
+		fmt.Fprintln(stdout, "No migrations found")
+		return nil
+	}
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tBATCH\tNAME\tSTATE\tAPPLIED AT")
+	for _, status := range statuses {
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\n", status.MigrationID, status.BatchID, status.Name, statusState(status), appliedAt(status))
+	}
+	return w.Flush()
+}
+
+func statusState(status migrate.MigrationStatus) string {
+	switch {
+	case status.Dirty:
+		return fmt.Sprintf("dirty (%d statements done)", status.Done)
+	case !status.FileExists:
+		return "missing file"
+	case status.Migrated:
+		return "migrated"
+	}
+	return "pending"
+}
+
+func appliedAt(status migrate.MigrationStatus) string {
+	if status.AppliedAt.IsZero() {
+		return "-"
+	}
+	return status.AppliedAt.Format(migrate.TIMESTAMP_LAYOUT)
+}
+
+func runValidate(c *config, args []string, stdout io.Writer) error {
+	if err := migrate.Make(nil, c.path).Validate(); err != nil {
+		return &exitError{EXIT_INVALID, err}
+	}
+	fmt.Fprintln(stdout, "Migration files are valid")
+	return nil
+}
+
+func runVerify(c *config, args []string, stdout io.Writer) error {
+	m, err := c.migration()
+	if err != nil {
+		return err
+	}
+	drifts, err := m.Verify()
+	if err != nil {
+		return &exitError{EXIT_FAILURE, err}
+	}
+	if len(drifts) < 1 {
+		fmt.Fprintln(stdout, "No applied migrations have changed")
+		return nil
+	}
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tRECORDED\tCURRENT")
+	for _, drift := range drifts {
+		current := drift.Current
+		if len(current) < 1 {
+			current = "missing file"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", drift.MigrationID, drift.Name, drift.Recorded, current)
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	return &exitError{EXIT_DRIFT, migrate.ErrDrift}
+}
+
+// database connects to the configured schema and checks that it is reachable
+func (c *config) database() (*sql.DB, error) {
+	db, err := c.connection()
+	if err != nil {
+		return nil, &exitError{EXIT_CONFIG, err}
+	}
+	if err = db.Ping(); err != nil {
+		return nil, &exitError{EXIT_CONNECTION, err}
+	}
+	return db, nil
+}
+
+func (c *config) migration() (*migrate.Migration, error) {
+	if len(c.path) < 1 {
+		return nil, &exitError{EXIT_CONFIG, errors.New("no migrations path given; use -path or set " + ENV_PATH)}
+	}
+	policy, err := missingFilePolicy(c.missingFiles)
+	if err != nil {
+		return nil, err
+	}
+	db, err := c.database()
+	if err != nil {
+		return nil, err
+	}
+	options := []migrate.Option{migrate.WithConnection(db), migrate.WithLockTimeout(c.lockTimeout), migrate.WithTable(c.table), migrate.WithAppVersion(c.appVersion)}
+	if c.lockFailFast {
+		options = append(options, migrate.WithLockFailFast())
+	}
+	if c.dryRun {
+		options = append(options, migrate.WithDryRun())
+	}
+	if c.strict {
+		options = append(options, migrate.WithStrictChecksums())
+	}
+	if len(policy) > 0 {
+		options = append(options, migrate.WithMissingFiles(policy))
+	}
+	return migrate.New(migrate.FromDB(db), migrate.MakeFileSource(c.path), options...), nil
+}
+
+// report prints the outcome of a run, or the plan in dry-run mode
+func (c *config) report(m *migrate.Migration, result migrate.Result, stdout io.Writer) {
+	if c.dryRun {
+		fmt.Fprint(stdout, m.Plan().String())
+		return
+	}
+	fmt.Fprintln(stdout, result.String())
+}
+
+// missingFilePolicy parses the -missing-files flag, which only some commands
+// have; it is empty for the others
+func missingFilePolicy(value string) (migrate.MissingFilePolicy, error) {
+	if len(value) < 1 {
+		return "", nil
+	}
+	for _, policy := range migrate.MissingFilePolicies {
+		if string(policy) == value {
+			return policy, nil
+		}
+	}
+	return "", usageErr(fmt.Sprintf("unknown -missing-files policy '%s'; use warn, error, archive or delete", value))
+}
+
+func usageErr(message string) error {
+	return &exitError{EXIT_USAGE, errors.New(message)}
+}
+
+func exitCode(err error) int {
+	if errors.Is(err, migrate.ErrLocked) {
+		return EXIT_LOCKED
+	}
+	if errors.Is(err, migrate.ErrDrift) {
+		return EXIT_DRIFT
+	}
+	if errors.Is(err, migrate.ErrDirty) {
+		return EXIT_DIRTY
+	}
+	if errors.Is(err, migrate.ErrMissingFile) {
+		return EXIT_INCONSISTENT
+	}
+	var exit *exitError
+	if errors.As(err, &exit) {
+		return exit.code
+	}
+	return EXIT_FAILURE
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
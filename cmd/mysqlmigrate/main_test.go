@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blainemoser/MySqlMigrate/migrate"
+)
+
+func TestDataSource(t *testing.T) {
+	c := &config{dsn: "root:secret@tcp(db.internal:3307)/app?tls=skip-verify&charset=utf8mb4&parseTime=true&timeout=5s"}
+	dsn, err := c.dataSource()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(dsn, "root:secret@tcp(db.internal:3307)/app?") {
+		t.Errorf("expected the address, credentials and schema from the DSN, got '%s'", dsn)
+	}
+	for _, param := range []string{"tls=skip-verify", "charset=utf8mb4", "parseTime=true", "timeout=5s"} {
+		if !strings.Contains(dsn, param) {
+			t.Errorf("expected the DSN to keep '%s', got '%s'", param, dsn)
+		}
+	}
+	c.schema = "other"
+	if dsn, _ = c.dataSource(); !strings.Contains(dsn, "/other?") {
+		t.Errorf("expected -schema to override the DSN, got '%s'", dsn)
+	}
+}
+
+func TestClose(t *testing.T) {
+	c := &config{dsn: "root:secret@tcp(127.0.0.1:3306)/app"}
+	conn, err := c.connection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.close()
+	if err = conn.Ping(); err == nil || !strings.Contains(err.Error(), "closed") {
+		t.Errorf("expected the connection pool to have been closed, got %v", err)
+	}
+	if len(c.closers) > 0 {
+		t.Errorf("expected the pools to be forgotten once closed")
+	}
+}
+
+func TestDataSourceErrors(t *testing.T) {
+	for _, dsn := range []string{"", "root:secret@tcp(127.0.0.1)/", "not a dsn"} {
+		c := &config{dsn: dsn}
+		if _, err := c.dataSource(); err == nil {
+			t.Errorf("expected an error for DSN '%s'", dsn)
+		}
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	valid := fmt.Sprintf("create_users.%d.sql", 1)
+	if err := os.WriteFile(filepath.Join(dir, valid), []byte(migrate.MIGRATE_DEFAULT), 0700); err != nil {
+		t.Fatal(err)
+	}
+	os.Unsetenv(ENV_DSN)
+	cases := map[string]struct {
+		args []string
+		code int
+	}{
+		"no command":                   {[]string{}, EXIT_USAGE},
+		"unknown command":              {[]string{"sideways"}, EXIT_USAGE},
+		"unknown flag":                 {[]string{"up", "-nope"}, EXIT_USAGE},
+		"missing dsn":                  {[]string{"up", "-path", dir}, EXIT_CONFIG},
+		"create no name":               {[]string{"create", "-path", dir}, EXIT_USAGE},
+		"to no target":                 {[]string{"to", "-path", dir}, EXIT_USAGE},
+		"force no state":               {[]string{"force", "-path", dir, "create_users"}, EXIT_USAGE},
+		"repair yes and interactive":   {[]string{"repair", "-yes", "-interactive"}, EXIT_USAGE},
+		"unknown missing files policy": {[]string{"up", "-path", dir, "-missing-files", "keep"}, EXIT_USAGE},
+		"negative steps":               {[]string{"down", "-steps", "-1"}, EXIT_USAGE},
+		"steps on status":              {[]string{"status", "-steps", "1"}, EXIT_USAGE},
+		"validate":                     {[]string{"validate", "-path", dir}, EXIT_OK},
+		"validate absent":              {[]string{"validate", "-path", filepath.Join(dir, "absent")}, EXIT_INVALID},
+	}
+	for name, tc := range cases {
+		var stdout, stderr bytes.Buffer
+		if code := run(tc.args, &stdout, &stderr); code != tc.code {
+			t.Errorf("%s: expected exit code %d, got %d (%s)", name, tc.code, code, stderr.String())
+		}
+	}
+}
+
+func TestValidateInvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "broken.1.sql"), []byte("CREATE TABLE t (id INT);"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"validate", "-path", dir}, &stdout, &stderr); code != EXIT_INVALID {
+		t.Errorf("expected exit code %d for a file without [DIRECTION], got %d", EXIT_INVALID, code)
+	}
+}
+
+func TestExitCodeErrors(t *testing.T) {
+	cases := map[error]int{
+		migrate.ErrLocked:      EXIT_LOCKED,
+		migrate.ErrDrift:       EXIT_DRIFT,
+		migrate.ErrDirty:       EXIT_DIRTY,
+		migrate.ErrMissingFile: EXIT_INCONSISTENT,
+	}
+	for cause, expected := range cases {
+		err := &exitError{EXIT_MIGRATION, fmt.Errorf("up: %w", cause)}
+		if code := exitCode(err); code != expected {
+			t.Errorf("expected exit code %d for '%s', got %d", expected, cause.Error(), code)
+		}
+	}
+}
+
+func TestAsk(t *testing.T) {
+	input := bufio.NewReader(strings.NewReader(" Yes\nr\n"))
+	var stdout bytes.Buffer
+	if answer := ask(input, &stdout, "Fix it? "); answer != "yes" || stdout.String() != "Fix it? " {
+		t.Errorf("expected the answer 'yes' to the question, got '%s' to '%s'", answer, stdout.String())
+	}
+	if answer := ask(input, &stdout, "Resume? "); answer != "r" {
+		t.Errorf("expected the answer on the next line, got '%s'", answer)
+	}
+	if answer := ask(input, &stdout, "Again? "); answer != "" {
+		t.Errorf("expected no answer at the end of the input, got '%s'", answer)
+	}
+}
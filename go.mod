@@ -6,6 +6,7 @@ require (
 	github.com/blainemoser/MySqlDB v0.0.0-20230405121127-e48408c8135a
 	github.com/blainemoser/TrySql v0.0.0-20230405105758-44a5c58842cb
 	github.com/go-sql-driver/mysql v1.7.0
+	github.com/mattn/go-sqlite3 v1.14.16
 )
 
 require (
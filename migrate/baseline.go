@@ -0,0 +1,88 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// BASELINE_BATCH is the batch of the migrations recorded by Baseline. Other
+	// batches are numbered by the time they were run.
+	BASELINE_BATCH = 1
+)
+
+// Baseline records every migration up to and including the target (see
+// MigrateTo) as having been run, without running it, for adopting migrations on
+// a database whose tables already exist. The migrations table is created if it
+// does not exist. The migrations are recorded in their own batch,
+// BASELINE_BATCH, which MigrateDown and MigrateDownSteps do not reverse and
+// MigrateTo refuses to reverse, and later migrations are run as normal.
+func (m *Migration) Baseline(target string) (message string, err error) {
+	if m.dryRun {
+		err = errors.New("migrations cannot be baselined in dry-run mode")
+		return
+	}
+	err = m.withLock(func() error {
+		m.direction = true
+		if err := m.prepare(); err != nil {
+			return err
+		}
+		if err := m.checkDirty(); err != nil {
+			return err
+		}
+		baselined, err := m.baseline(target)
+		message = fmt.Sprintf("Baselined %d migrations up to '%s'", baselined, target)
+		return err
+	})
+	return
+}
+
+func (m *Migration) baseline(target string) (baselined int, err error) {
+	present, err := m.presentRecords()
+	if err != nil {
+		return
+	}
+	index, err := targetIndex(present, target)
+	if err != nil {
+		return
+	}
+	for _, row := range present[:index+1] {
+		migrated, err := getMigrated(row["migrated"])
+		if err != nil {
+			return baselined, err
+		}
+		if migrated {
+			continue
+		}
+		properties, err := m.baselineProperties(row)
+		if err != nil {
+			return baselined, err
+		}
+		if err = m.database.UpdateRecord(m.table(), "migration_id", properties); err != nil {
+			return baselined, err
+		}
+		baselined++
+	}
+	return
+}
+
+func (m *Migration) baselineProperties(row map[string]interface{}) (map[string]interface{}, error) {
+	name, id, err := getNameAndID(row)
+	if err != nil {
+		return nil, err
+	}
+	properties := map[string]interface{}{
+		"migration_id": id,
+		"migrated":     "1",
+		"batch_id":     BASELINE_BATCH,
+	}
+	if _, ok := m.funcs[name]; ok {
+		return properties, nil
+	}
+	up, down, err := m.source.Read(name)
+	if err != nil {
+		return nil, err
+	}
+	properties["checksum"] = checksum(up, down)
+	return properties, nil
+}
@@ -0,0 +1,86 @@
+package migrate
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBaseline(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	// The table already exists, as it would on a database adopting migrations
+	if _, err = db.Exec("CREATE TABLE gadgets (id INT(6) UNSIGNED AUTO_INCREMENT PRIMARY KEY, name VARCHAR(255) NOT NULL)", nil); err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0)
+	for i, content := range testSquashMigrations {
+		name, err := writeMigration(path, fmt.Sprintf("baseline_%d", i+1), int64(i+1), content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if _, err = Make(db, path).Baseline("missing"); err == nil {
+		t.Errorf("expected an error for a target that does not exist")
+	}
+	message, err := Make(db, path).Baseline("baseline_2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message != "Baselined 2 migrations up to 'baseline_2'" {
+		t.Errorf("unexpected message '%s'", message)
+	}
+	checkBaselined(t, path, map[string]int64{names[0]: BASELINE_BATCH, names[1]: BASELINE_BATCH, names[2]: 0})
+	if count := countGadgets(t); count != 0 {
+		t.Errorf("expected the baselined insert not to have been run, found %d gadgets", count)
+	}
+	if drifts, err := Make(db, path).Verify(); err != nil || len(drifts) > 0 {
+		t.Errorf("expected no drift after the baseline, got %v (%v)", drifts, err)
+	}
+
+	// Later migrations are run and reversed as normal, but the baseline is not reversed
+	if _, err = Make(db, path).MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, path, map[string]bool{names[0]: true, names[1]: true, names[2]: true})
+	if _, err = Make(db, path).MigrateTo(names[0]); err == nil {
+		t.Errorf("expected an error reversing a baselined migration with MigrateTo")
+	}
+	checkMigrated(t, path, map[string]bool{names[0]: true, names[1]: true, names[2]: true})
+	if _, err = Make(db, path).MigrateTo(names[1]); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, path, map[string]bool{names[0]: true, names[1]: true, names[2]: false})
+	if _, err = Make(db, path).MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err = Make(db, path).MigrateDown(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkMigrated(t, path, map[string]bool{names[0]: true, names[1]: true, names[2]: false})
+	if _, err = Make(db, path).MigrateDownSteps(5); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, path, map[string]bool{names[0]: true, names[1]: true})
+}
+
+func checkBaselined(t *testing.T, path string, expected map[string]int64) {
+	statuses, err := Make(db, path).Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		batch, ok := expected[status.Name]
+		if !ok {
+			continue
+		}
+		if status.BatchID != batch || status.Migrated != (batch == BASELINE_BATCH) {
+			t.Errorf("expected '%s' to be in batch %d, got %d (migrated = %t)", status.Name, batch, status.BatchID, status.Migrated)
+		}
+	}
+}
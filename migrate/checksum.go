@@ -0,0 +1,100 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	CHECKSUM_QUERY = "SELECT migration_id, name, checksum FROM [table] WHERE migrated = 1 ORDER BY migration_id ASC"
+)
+
+// ErrDrift is returned in strict mode (see WithStrictChecksums) when a migration
+// that has been run has since been changed
+var ErrDrift = errors.New("applied migrations have changed")
+
+// Drift describes a migration that has been run and whose file has since changed
+type Drift struct {
+	Name        string
+	MigrationID int64
+	Recorded    string // the checksum recorded when the migration was run
+	Current     string // the checksum of the file now; empty if the file is missing
+}
+
+// Verify compares every migration that has been run against its file and returns
+// those whose contents have changed since. Migrations run before checksums were
+// recorded are not checked.
+func (m *Migration) Verify() ([]Drift, error) {
+	drifts := make([]Drift, 0)
+	hasColumn, err := m.hasChecksumColumn()
+	if err != nil || !hasColumn {
+		return drifts, err
+	}
+	onDisk, err := m.filesOnDisk()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := m.query(CHECKSUM_QUERY, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		recorded, _ := row["checksum"].(string)
+		if len(recorded) < 1 {
+			continue
+		}
+		name, id, err := getNameAndID(row)
+		if err != nil {
+			return nil, err
+		}
+		current := ""
+		if _, ok := m.funcs[name]; onDisk[name] && !ok {
+			up, down, err := m.source.Read(name)
+			if err != nil {
+				return nil, err
+			}
+			current = checksum(up, down)
+		}
+		if current != recorded {
+			drifts = append(drifts, Drift{Name: name, MigrationID: id, Recorded: recorded, Current: current})
+		}
+	}
+	return drifts, nil
+}
+
+// checkDrift fails in strict mode if any applied migration has changed
+func (m *Migration) checkDrift() error {
+	if !m.strict {
+		return nil
+	}
+	drifts, err := m.Verify()
+	if err != nil {
+		return err
+	}
+	if len(drifts) < 1 {
+		return nil
+	}
+	names := make([]string, 0, len(drifts))
+	for _, drift := range drifts {
+		names = append(names, drift.Name)
+	}
+	return fmt.Errorf("%w: %s", ErrDrift, strings.Join(names, ", "))
+}
+
+func (m *Migration) hasChecksumColumn() (bool, error) {
+	hasTable, err := m.checkHasTable()
+	if err != nil || !hasTable {
+		return false, err
+	}
+	return m.hasColumn("checksum")
+}
+
+// checksum hashes the migration as the contents of its file, so that checksums
+// recorded before migrations were read from sources still match
+func checksum(up, down string) string {
+	sum := sha256.Sum256([]byte(up + DIRECTION_MARKER + down))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,85 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+const LEGACY_MIGS_TABLE = `CREATE TABLE migrations (
+	id INT(6) UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+	migration_id BIGINT UNSIGNED,
+	batch_id BIGINT UNSIGNED,
+	name VARCHAR(1000) NOT NULL,
+	migrated TINYINT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+)`
+
+func TestVerify(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	created, err := writeMigration(path, "create_gadgets_table", 1, TEST_GADGETS_TABLE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Make(db, path).MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	drifts, err := Make(db, path).Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifts) > 0 {
+		t.Errorf("expected no drift, got %+v", drifts)
+	}
+
+	// Someone edits the applied migration
+	if err = writeFile(fmt.Sprintf("%s/%s.sql", path, created), TEST_GADGETS_TABLE+"\n-- edited\n"); err != nil {
+		t.Fatal(err)
+	}
+	if drifts, err = Make(db, path).Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if len(drifts) != 1 || drifts[0].Name != created || drifts[0].Current == drifts[0].Recorded {
+		t.Errorf("expected '%s' to have drifted, got %+v", created, drifts)
+	}
+	pending, err := writeMigration(path, "alter_gadgets_add_colour", 2, TEST_ALTER_GADGETS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Make(db, path, WithStrictChecksums()).MigrateUp(); !errors.Is(err, ErrDrift) {
+		t.Errorf("expected ErrDrift in strict mode, got %v", err)
+	}
+	checkMigrated(t, path, map[string]bool{pending: false})
+	if _, err = Make(db, path).MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, path, map[string]bool{pending: true})
+}
+
+func TestChecksumColumnAdded(t *testing.T) {
+	reset()
+	if _, err := db.Exec(LEGACY_MIGS_TABLE, nil); err != nil {
+		t.Fatal(err)
+	}
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reset()
+	m := Make(db, path)
+	if _, err = m.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	hasColumn, err := m.hasChecksumColumn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasColumn {
+		t.Errorf("expected the checksum column to have been added")
+	}
+}
@@ -0,0 +1,400 @@
+package migrate
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	POSTGRES_MIGS_TABLE = `CREATE TABLE [table] (
+	id SERIAL PRIMARY KEY,
+	migration_id BIGINT,
+	batch_id BIGINT,
+	name VARCHAR(1000) NOT NULL,
+	migrated SMALLINT,
+	checksum VARCHAR(64) NULL,
+	duration_ms BIGINT NULL,
+	host VARCHAR(255) NULL,
+	db_user VARCHAR(255) NULL,
+	app_version VARCHAR(255) NULL,
+	direction VARCHAR(4) NULL,
+	dirty SMALLINT NULL,
+	statements_done INT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)`
+	SQLITE_MIGS_TABLE = `CREATE TABLE [table] (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	migration_id BIGINT,
+	batch_id BIGINT,
+	name VARCHAR(1000) NOT NULL,
+	migrated TINYINT,
+	checksum VARCHAR(64) NULL,
+	duration_ms BIGINT NULL,
+	host VARCHAR(255) NULL,
+	db_user VARCHAR(255) NULL,
+	app_version VARCHAR(255) NULL,
+	direction VARCHAR(4) NULL,
+	dirty SMALLINT NULL,
+	statements_done INT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)`
+
+	MYSQL_TABLE_QUERY     = "SELECT COUNT(*) AS total FROM information_schema.tables WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND table_name = ?"
+	MYSQL_COLUMN_QUERY    = "SELECT COUNT(*) AS total FROM information_schema.columns WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND table_name = ? AND column_name = ?"
+	POSTGRES_TABLE_QUERY  = "SELECT COUNT(*) AS total FROM information_schema.tables WHERE table_schema = COALESCE(NULLIF(?, ''), current_schema()) AND table_name = ?"
+	POSTGRES_COLUMN_QUERY = "SELECT COUNT(*) AS total FROM information_schema.columns WHERE table_schema = COALESCE(NULLIF(?, ''), current_schema()) AND table_name = ? AND column_name = ?"
+	SQLITE_TABLE_QUERY    = "SELECT COUNT(*) AS total FROM pragma_table_list WHERE schema = COALESCE(NULLIF(?1, ''), 'main') AND name = ?2 AND type = 'table'"
+	SQLITE_COLUMN_QUERY   = "SELECT COUNT(*) AS total FROM pragma_table_info(?2, COALESCE(NULLIF(?1, ''), 'main')) WHERE name = ?3"
+	MYSQL_USER_QUERY      = "SELECT CURRENT_USER() AS name"
+	POSTGRES_USER_QUERY   = "SELECT current_user AS name"
+
+	POSTGRES_LOCK_KEY     = "hashtext('mysqlmigrate.' || COALESCE(NULLIF(?, ''), current_schema()) || '.' || ?)"
+	POSTGRES_LOCK_QUERY   = "SELECT pg_try_advisory_lock(" + POSTGRES_LOCK_KEY + ") AS locked"
+	POSTGRES_UNLOCK_QUERY = "SELECT pg_advisory_unlock(" + POSTGRES_LOCK_KEY + ") AS released"
+	LOCK_POLL_INTERVAL    = 100 * time.Millisecond
+	LOCK_NAME_PREFIX      = "mysqlmigrate."
+	MYSQL_LOCK_NAME_MAX   = 64 // the longest name GET_LOCK accepts
+)
+
+// Dialect adapts the migrations to a database engine. It owns the migrations
+// table, the bookkeeping queries, locking and the splitting of migrations into
+// statements. Bookkeeping queries are written with ? placeholders, which Bind
+// rewrites for the engine, and name the migrations table [table], which is
+// replaced with its quoted name (see WithTable).
+type Dialect interface {
+	// Name names the engine
+	Name() string
+	// Bind rewrites the ? placeholders of a bookkeeping query for the engine
+	Bind(query string) string
+	// Quote quotes an identifier, such as the name of a table or schema
+	Quote(identifier string) string
+	// CreateTable returns the statement that creates the migrations table
+	CreateTable() string
+	// TableQuery returns a query for the number of tables, as total, in the schema
+	// named by its first parameter, or the current schema if it is empty, that are
+	// named by its second
+	TableQuery() string
+	// ColumnQuery returns a query for the number of columns, as total, of the table
+	// in the schema named by its first parameter (as for TableQuery) and named by
+	// its second, that are named by its third
+	ColumnQuery() string
+	// UserQuery returns a query for the database user, as name, or an empty
+	// string if the engine has no users
+	UserQuery() string
+	// InsertQuery returns the statement that inserts a record with the fields into
+	// the migrations table, and whether it returns the id of the new record as a row
+	InsertQuery(table string, fields []string) (query string, returning bool)
+	// UpdateQuery returns the statement that updates the fields of the record in
+	// the migrations table identified by the key, which is its last parameter
+	UpdateQuery(table, key string, fields []string) string
+	// Lock takes a lock that keeps other processes from running the migrations
+	// of the table, in the schema or the current schema if it is empty, waiting
+	// up to the timeout, and returns ErrLocked if it could not. The session keeps
+	// the same connection until the lock is released.
+	Lock(session Execer, schema, table string, timeout time.Duration) (unlock func() error, err error)
+	// Split splits SQL into its statements
+	Split(sql string) []string
+	// ImplicitCommit returns the keyword of the first statement that would commit
+	// a transaction implicitly
+	ImplicitCommit(statements []string) (string, bool)
+	// ErrorNumber returns the number of the database error that err wraps, or
+	// zero if it wraps none (see MigrationError)
+	ErrorNumber(err error) uint16
+}
+
+type (
+	mysqlDialect    struct{}
+	postgresDialect struct{}
+	sqliteDialect   struct{}
+)
+
+var (
+	// MySQL is the default dialect
+	MySQL Dialect = mysqlDialect{}
+	// Postgres is the dialect for PostgreSQL. Migrations are locked with an
+	// advisory lock on the current schema.
+	Postgres Dialect = postgresDialect{}
+	// SQLite is the dialect for SQLite. Migrations are not locked, as SQLite
+	// serialises writes to the database itself.
+	SQLite Dialect = sqliteDialect{}
+)
+
+func (mysqlDialect) Name() string {
+	return "mysql"
+}
+
+func (mysqlDialect) Bind(query string) string {
+	return query
+}
+
+func (mysqlDialect) Quote(identifier string) string {
+	return "`" + strings.ReplaceAll(identifier, "`", "``") + "`"
+}
+
+func (mysqlDialect) CreateTable() string {
+	return MIGS_TABLE
+}
+
+func (mysqlDialect) TableQuery() string {
+	return MYSQL_TABLE_QUERY
+}
+
+func (mysqlDialect) ColumnQuery() string {
+	return MYSQL_COLUMN_QUERY
+}
+
+func (mysqlDialect) UserQuery() string {
+	return MYSQL_USER_QUERY
+}
+
+func (mysqlDialect) InsertQuery(table string, fields []string) (string, bool) {
+	return insertQuery(table, fields), false
+}
+
+func (mysqlDialect) UpdateQuery(table, key string, fields []string) string {
+	return updateQuery(table, key, fields)
+}
+
+// Lock takes a named lock (GET_LOCK), which is held by the session
+func (mysqlDialect) Lock(session Execer, schema, table string, timeout time.Duration) (func() error, error) {
+	name, err := lockName(session, schema, table)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := session.QueryRaw(LOCK_QUERY, []interface{}{name, lockSeconds(timeout)})
+	if err != nil {
+		return nil, err
+	}
+	locked, ok := rows[0]["locked"].(int64)
+	if !ok {
+		return nil, fmt.Errorf("could not acquire the migration lock '%s'", name)
+	}
+	if locked != 1 {
+		return nil, ErrLocked
+	}
+	return func() error {
+		_, err := session.QueryRaw(UNLOCK_QUERY, []interface{}{name})
+		return err
+	}, nil
+}
+
+func (mysqlDialect) Split(sql string) []string {
+	return splitStatements(sql)
+}
+
+func (mysqlDialect) ImplicitCommit(statements []string) (string, bool) {
+	return findImplicitCommit(statements)
+}
+
+func (mysqlDialect) ErrorNumber(err error) uint16 {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number
+	}
+	return 0
+}
+
+func (postgresDialect) Name() string {
+	return "postgres"
+}
+
+// Bind numbers the placeholders, $1, $2 and so on
+func (postgresDialect) Bind(query string) string {
+	var b strings.Builder
+	n := 0
+	quoted := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if c == '\'' {
+			quoted = !quoted
+		}
+		if c == '?' && !quoted {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+func (postgresDialect) Quote(identifier string) string {
+	return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+}
+
+func (postgresDialect) CreateTable() string {
+	return POSTGRES_MIGS_TABLE
+}
+
+func (postgresDialect) TableQuery() string {
+	return POSTGRES_TABLE_QUERY
+}
+
+func (postgresDialect) ColumnQuery() string {
+	return POSTGRES_COLUMN_QUERY
+}
+
+func (postgresDialect) UserQuery() string {
+	return POSTGRES_USER_QUERY
+}
+
+// InsertQuery returns the id of the new record, as PostgreSQL drivers do not
+// support LastInsertId
+func (d postgresDialect) InsertQuery(table string, fields []string) (string, bool) {
+	return d.Bind(insertQuery(table, fields) + " RETURNING id"), true
+}
+
+// UpdateQuery also updates updated_at, which PostgreSQL does not do by itself
+func (d postgresDialect) UpdateQuery(table, key string, fields []string) string {
+	return d.Bind(updateQuery(table, key, append(fields, "updated_at = CURRENT_TIMESTAMP")))
+}
+
+// Lock takes an advisory lock on the table, polling until the timeout
+func (d postgresDialect) Lock(session Execer, schema, table string, timeout time.Duration) (func() error, error) {
+	key := []interface{}{schema, table}
+	deadline := time.Now().Add(timeout)
+	for {
+		rows, err := session.QueryRaw(d.Bind(POSTGRES_LOCK_QUERY), key)
+		if err != nil {
+			return nil, err
+		}
+		if locked, _ := rows[0]["locked"].(bool); locked {
+			return func() error {
+				_, err := session.QueryRaw(d.Bind(POSTGRES_UNLOCK_QUERY), key)
+				return err
+			}, nil
+		}
+		if !time.Now().Before(deadline) {
+			return nil, ErrLocked
+		}
+		time.Sleep(LOCK_POLL_INTERVAL)
+	}
+}
+
+func (postgresDialect) Split(sql string) []string {
+	return splitWith(postgresRules, sql)
+}
+
+// ImplicitCommit finds nothing, as PostgreSQL runs DDL in transactions
+func (postgresDialect) ImplicitCommit(statements []string) (string, bool) {
+	return "", false
+}
+
+// ErrorNumber finds nothing, as PostgreSQL identifies its errors by SQLSTATE
+// codes rather than numbers
+func (postgresDialect) ErrorNumber(err error) uint16 {
+	return 0
+}
+
+func (sqliteDialect) Name() string {
+	return "sqlite"
+}
+
+func (sqliteDialect) Bind(query string) string {
+	return query
+}
+
+func (sqliteDialect) Quote(identifier string) string {
+	return postgresDialect{}.Quote(identifier)
+}
+
+func (sqliteDialect) CreateTable() string {
+	return SQLITE_MIGS_TABLE
+}
+
+func (sqliteDialect) TableQuery() string {
+	return SQLITE_TABLE_QUERY
+}
+
+func (sqliteDialect) ColumnQuery() string {
+	return SQLITE_COLUMN_QUERY
+}
+
+func (sqliteDialect) UserQuery() string {
+	return ""
+}
+
+func (sqliteDialect) InsertQuery(table string, fields []string) (string, bool) {
+	return insertQuery(table, fields), false
+}
+
+// UpdateQuery also updates updated_at, which SQLite does not do by itself
+func (sqliteDialect) UpdateQuery(table, key string, fields []string) string {
+	return updateQuery(table, key, append(fields, "updated_at = CURRENT_TIMESTAMP"))
+}
+
+func (sqliteDialect) Lock(session Execer, schema, table string, timeout time.Duration) (func() error, error) {
+	return func() error { return nil }, nil
+}
+
+func (sqliteDialect) Split(sql string) []string {
+	return splitWith(sqliteRules, sql)
+}
+
+// ImplicitCommit finds nothing, as SQLite runs DDL in transactions
+func (sqliteDialect) ImplicitCommit(statements []string) (string, bool) {
+	return "", false
+}
+
+// ErrorNumber finds nothing, as the SQLite driver is not known to the package
+func (sqliteDialect) ErrorNumber(err error) uint16 {
+	return 0
+}
+
+// insertQuery builds an insert statement with ? placeholders
+func insertQuery(table string, fields []string) string {
+	placeholders := make([]string, len(fields))
+	for i := range fields {
+		placeholders[i] = "?"
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(fields, ", "), strings.Join(placeholders, ", "))
+}
+
+// updateQuery builds an update statement with ? placeholders. Fields that are
+// already assignments are used as they are.
+func updateQuery(table, key string, fields []string) string {
+	assignments := make([]string, len(fields))
+	for i, field := range fields {
+		assignments[i] = field
+		if !strings.Contains(field, "=") {
+			assignments[i] = field + " = ?"
+		}
+	}
+	return fmt.Sprintf("UPDATE %s SET %s WHERE %s = ?", table, strings.Join(assignments, ", "), key)
+}
+
+// lockName names the MySQL lock after the schema, or the current schema, and the
+// table, as named locks are shared by every schema on the server
+func lockName(session Execer, schema, table string) (string, error) {
+	if len(schema) > 0 {
+		return mysqlLockName(schema, table), nil
+	}
+	rows, err := session.QueryRaw(SCHEMA_QUERY, nil)
+	if err != nil {
+		return "", err
+	}
+	if len(rows) < 1 {
+		return "", errors.New("could not determine the current schema")
+	}
+	return mysqlLockName(fmt.Sprintf("%v", rows[0]["name"]), table), nil
+}
+
+// mysqlLockName is mysqlmigrate.<schema>.<table>, or a hash of the schema and
+// table when that is longer than GET_LOCK allows
+func mysqlLockName(schema, table string) string {
+	name := LOCK_NAME_PREFIX + schema + "." + table
+	if len(name) <= MYSQL_LOCK_NAME_MAX {
+		return name
+	}
+	sum := sha1.Sum([]byte(schema + "." + table))
+	return LOCK_NAME_PREFIX + hex.EncodeToString(sum[:])
+}
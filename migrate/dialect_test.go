@@ -0,0 +1,121 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestDialectQueries(t *testing.T) {
+	fields, inserts := recordFields(map[string]interface{}{
+		"migration_id": 3,
+		"migrated":     "1",
+		"batch_id":     "100",
+	}, "migration_id")
+	if fmt.Sprint(inserts) != "[100 1]" {
+		t.Errorf("unexpected inserts %v", inserts)
+	}
+	cases := map[Dialect][2]string{
+		MySQL: {
+			"INSERT INTO migrations (batch_id, migrated) VALUES (?, ?)",
+			"UPDATE migrations SET batch_id = ?, migrated = ? WHERE migration_id = ?",
+		},
+		Postgres: {
+			"INSERT INTO migrations (batch_id, migrated) VALUES ($1, $2) RETURNING id",
+			"UPDATE migrations SET batch_id = $1, migrated = $2, updated_at = CURRENT_TIMESTAMP WHERE migration_id = $3",
+		},
+		SQLite: {
+			"INSERT INTO migrations (batch_id, migrated) VALUES (?, ?)",
+			"UPDATE migrations SET batch_id = ?, migrated = ?, updated_at = CURRENT_TIMESTAMP WHERE migration_id = ?",
+		},
+	}
+	for dialect, expected := range cases {
+		if insert, _ := dialect.InsertQuery("migrations", fields); insert != expected[0] {
+			t.Errorf("%s: unexpected insert '%s'", dialect.Name(), insert)
+		}
+		if update := dialect.UpdateQuery("migrations", "migration_id", fields); update != expected[1] {
+			t.Errorf("%s: unexpected update '%s'", dialect.Name(), update)
+		}
+	}
+}
+
+func TestDialectQuote(t *testing.T) {
+	for dialect, expected := range map[Dialect]string{MySQL: "`my``table`", Postgres: `"my""table"`, SQLite: `"my""table"`} {
+		if quoted := dialect.Quote("my" + dialect.Quote("")[:1] + "table"); quoted != expected {
+			t.Errorf("%s: unexpected identifier %s", dialect.Name(), quoted)
+		}
+	}
+	m := New(FromDB(nil), MakeMemorySource(), WithTable("schema_migrations"), WithTableSchema("bookkeeping"))
+	if query := m.bind(EXISTS_QUERY); query != "SELECT count(*) as taken FROM `bookkeeping`.`schema_migrations` WHERE name = ?;" {
+		t.Errorf("unexpected query '%s'", query)
+	}
+}
+
+func TestPostgresBind(t *testing.T) {
+	query := Postgres.Bind("SELECT * FROM migrations WHERE name = ? AND note = 'why?' AND migrated = ?")
+	if query != "SELECT * FROM migrations WHERE name = $1 AND note = 'why?' AND migrated = $2" {
+		t.Errorf("unexpected query '%s'", query)
+	}
+}
+
+func TestLockName(t *testing.T) {
+	if name := mysqlLockName("app", "migrations"); name != "mysqlmigrate.app.migrations" {
+		t.Errorf("unexpected lock name '%s'", name)
+	}
+	schema := strings.Repeat("s", 40)
+	long := mysqlLockName(schema, "migrations")
+	if len(long) > MYSQL_LOCK_NAME_MAX || !strings.HasPrefix(long, LOCK_NAME_PREFIX) {
+		t.Errorf("expected a lock name of at most %d characters, got '%s'", MYSQL_LOCK_NAME_MAX, long)
+	}
+	if other := mysqlLockName(schema, "schema_migrations"); other == long {
+		t.Errorf("expected the hashed lock names of different tables to differ")
+	}
+}
+
+func TestErrorNumber(t *testing.T) {
+	err := fmt.Errorf("statement failed: %w", &mysql.MySQLError{Number: 1050, Message: "table exists"})
+	if number := MySQL.ErrorNumber(err); number != 1050 {
+		t.Errorf("expected the number of the wrapped MySQL error, got %d", number)
+	}
+	if number := MySQL.ErrorNumber(errors.New("not from MySQL")); number != 0 {
+		t.Errorf("expected no number for another error, got %d", number)
+	}
+	if number := Postgres.ErrorNumber(err); number != 0 {
+		t.Errorf("expected PostgreSQL errors to have no number, got %d", number)
+	}
+}
+
+func TestDialectSplit(t *testing.T) {
+	cases := map[Dialect]map[string][]string{
+		Postgres: {
+			"CREATE FUNCTION touch() RETURNS trigger AS $$\nBEGIN\n\tNEW.updated_at = now();\n\tRETURN NEW;\nEND;\n$$ LANGUAGE plpgsql;\nSELECT $1::int;": {
+				"CREATE FUNCTION touch() RETURNS trigger AS $$\nBEGIN\n\tNEW.updated_at = now();\n\tRETURN NEW;\nEND;\n$$ LANGUAGE plpgsql",
+				"SELECT $1::int",
+			},
+			"SELECT $body$ a; b $body$; SELECT 'back\\'; SELECT 2;": {
+				"SELECT $body$ a; b $body$",
+				"SELECT 'back\\'",
+				"SELECT 2",
+			},
+			"# not a comment;": {"# not a comment"},
+		},
+		SQLite: {
+			"CREATE TRIGGER touch AFTER UPDATE ON gadgets BEGIN\n\tUPDATE gadgets SET name = CASE WHEN name = '' THEN 'x' ELSE name END WHERE id = NEW.id;\n\tSELECT 1;\nEND;\nSELECT 2;": {
+				"CREATE TRIGGER touch AFTER UPDATE ON gadgets BEGIN\n\tUPDATE gadgets SET name = CASE WHEN name = '' THEN 'x' ELSE name END WHERE id = NEW.id;\n\tSELECT 1;\nEND",
+				"SELECT 2",
+			},
+			"BEGIN; SELECT 1; COMMIT;": {"BEGIN", "SELECT 1", "COMMIT"},
+		},
+	}
+	for dialect, statements := range cases {
+		for sql, expected := range statements {
+			if split := dialect.Split(sql); !reflect.DeepEqual(split, expected) {
+				t.Errorf("%s: unexpected statements for %q:\n%q", dialect.Name(), sql, split)
+			}
+		}
+	}
+}
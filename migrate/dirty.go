@@ -0,0 +1,200 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	DIRTY_QUERY = "SELECT * FROM [table] WHERE dirty = 1 ORDER BY migration_id ASC"
+)
+
+// ErrDirty is returned when a migration failed part of the way through, leaving
+// the statements before the failure run. Migrations are not run until the
+// migration is finished with Resume or its state is set with Force.
+var ErrDirty = errors.New("a migration failed part of the way through")
+
+// progress returns the function that records how many statements of the
+// migration have been done. A migration that has done some of its statements is
+// dirty until it finishes. In a transaction, statements are only done once they
+// have been committed, which happens at the first statement that commits
+// implicitly. That ends the transaction, so every later statement commits too.
+// The statements before it are committed even if it fails, so the migration is
+// marked dirty before it runs.
+func (m *Migration) progress(result *MigrationResult, transactional bool) func(done int, statement, next string) error {
+	committed := !transactional
+	return func(done int, statement, next string) error {
+		if !committed {
+			if _, ok := m.dialect.ImplicitCommit([]string{statement}); ok {
+				committed = true
+			} else if _, ok := m.dialect.ImplicitCommit([]string{next}); !ok {
+				return nil
+			}
+		}
+		return m.database.UpdateRecord(m.table(), "migration_id", map[string]interface{}{
+			"migration_id":    result.MigrationID,
+			"dirty":           1,
+			"statements_done": done,
+			"direction":       result.Direction,
+		})
+	}
+}
+
+// checkDirty fails with ErrDirty if a migration has been left dirty
+func (m *Migration) checkDirty() error {
+	record, err := m.dirtyRecord()
+	if err != nil || record == nil {
+		return err
+	}
+	name, _, err := getNameAndID(record)
+	if err != nil {
+		return err
+	}
+	done, _ := getInt(record["statements_done"])
+	return fmt.Errorf("%w: '%s' (%s) failed after %d statements; finish it with Resume or set its state with Force", ErrDirty, name, getString(record["direction"]), done)
+}
+
+// dirtyRecord returns the record of the migration that has been left dirty, or
+// nil if there is none
+func (m *Migration) dirtyRecord() (map[string]interface{}, error) {
+	if !m.hasTable {
+		return nil, nil
+	}
+	if m.dryRun {
+		// The table is not upgraded in dry-run mode
+		if hasColumn, err := m.hasColumn("dirty"); err != nil || !hasColumn {
+			return nil, err
+		}
+	}
+	rows, err := m.query(DIRTY_QUERY, nil)
+	if err != nil || len(rows) < 1 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// Resume finishes the migration that was left dirty, in the direction in which
+// it failed, running its statements from the one that failed. Fix the cause of
+// the failure first. Other migrations are not run.
+func (m *Migration) Resume() (result Result, err error) {
+	if m.dryRun {
+		err = errors.New("migrations cannot be resumed in dry-run mode")
+		return
+	}
+	err = m.withLock(func() error {
+		if err := m.prepare(); err != nil {
+			return err
+		}
+		record, err := m.dirtyRecord()
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			return errors.New("no migration has been left dirty")
+		}
+		migration, err := m.resume(record)
+		if err != nil {
+			return err
+		}
+		result.Migrations = []MigrationResult{migration}
+		return m.writeSchema()
+	})
+	return
+}
+
+func (m *Migration) resume(record map[string]interface{}) (result MigrationResult, err error) {
+	_, id, err := getNameAndID(record)
+	if err != nil {
+		return
+	}
+	done := 0
+	if record["statements_done"] != nil {
+		if done, err = getInt(record["statements_done"]); err != nil {
+			return
+		}
+	}
+	m.direction = getString(record["direction"]) != DIRECTION_DOWN
+	m.migrations = make(map[int]*migrationFile)
+	if err = m.appendContents(record); err != nil {
+		return
+	}
+	metadata, err := m.runMetadata()
+	if err != nil {
+		return
+	}
+	batchID, err := m.nextBatch()
+	if err != nil {
+		return
+	}
+	file := m.migrations[int(id)]
+	result = m.newResult(file, int(id), batchID)
+	err = m.executeMigration(file, m.getProperties(file, int(id), batchID, metadata), &result, done)
+	return
+}
+
+// Force records the target (see MigrateTo) as run, or as not run, without
+// running anything, and clears its dirty state. Use it once a migration that
+// failed part of the way through has been finished or undone by hand.
+func (m *Migration) Force(target string, migrated bool) (message string, err error) {
+	if m.dryRun {
+		err = errors.New("migrations cannot be forced in dry-run mode")
+		return
+	}
+	err = m.withLock(func() error {
+		m.direction = true
+		if err := m.prepare(); err != nil {
+			return err
+		}
+		records, err := m.records()
+		if err != nil {
+			return err
+		}
+		index, err := targetIndex(records, target)
+		if err != nil {
+			return err
+		}
+		name, properties, err := m.forceProperties(records[index], migrated)
+		if err != nil {
+			return err
+		}
+		if err = m.database.UpdateRecord(m.table(), "migration_id", properties); err != nil {
+			return err
+		}
+		state := "not run"
+		if migrated {
+			state = "run"
+		}
+		message = fmt.Sprintf("Forced '%s' to be recorded as %s", name, state)
+		return nil
+	})
+	return
+}
+
+func (m *Migration) forceProperties(record map[string]interface{}, migrated bool) (string, map[string]interface{}, error) {
+	name, id, err := getNameAndID(record)
+	if err != nil {
+		return "", nil, err
+	}
+	properties := map[string]interface{}{
+		"migration_id":    id,
+		"migrated":        "0",
+		"dirty":           0,
+		"statements_done": nil,
+	}
+	if !migrated {
+		return name, properties, nil
+	}
+	properties["migrated"] = "1"
+	if batch, err := getBatchID(record["batch_id"]); err != nil || batch < 1 {
+		if properties["batch_id"], err = m.nextBatch(); err != nil {
+			return "", nil, err
+		}
+	}
+	if _, ok := m.funcs[name]; ok {
+		return name, properties, nil
+	}
+	if up, down, err := m.source.Read(name); err == nil {
+		properties["checksum"] = checksum(up, down)
+	}
+	return name, properties, nil
+}
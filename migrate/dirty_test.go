@@ -0,0 +1,167 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+const (
+	TEST_PARTIAL = `CREATE TABLE gadgets (id INT PRIMARY KEY, name VARCHAR(255) NOT NULL);
+INSERT INTO gadgets (id, name) VALUES (1, 'sprocket');
+INSERT INTO gadgets (id, name) SELECT id, name FROM %s;
+-- [DIRECTION]
+DROP TABLE gadgets;`
+	TEST_DML_THEN_DDL = `INSERT INTO gadgets (name) VALUES ('sprocket');
+ALTER TABLE %s ADD weight INT NULL;
+-- [DIRECTION]
+ALTER TABLE gadgets DROP weight;`
+)
+
+func TestDirty(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	name, err := writeMigration(path, "partial", 1, fmt.Sprintf(TEST_PARTIAL, "missing_table"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Make(db, path).MigrateUp(); err == nil {
+		t.Fatalf("expected the third statement to fail")
+	}
+	checkDirty(t, Make(db, path), name, 2)
+	if _, err = Make(db, path).MigrateUp(); !errors.Is(err, ErrDirty) {
+		t.Errorf("expected ErrDirty while a migration is dirty, got %v", err)
+	}
+	if _, err = Make(db, path).MigrateTo(name); !errors.Is(err, ErrDirty) {
+		t.Errorf("expected ErrDirty from MigrateTo while a migration is dirty, got %v", err)
+	}
+
+	// Resume runs the statements from the one that failed
+	if _, err = writeMigration(path, "partial", 1, fmt.Sprintf(TEST_PARTIAL, "(SELECT 2 AS id, 'widget' AS name) AS fixed")); err != nil {
+		t.Fatal(err)
+	}
+	result, err := Make(db, path).Resume()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Migrations) != 1 || result.Migrations[0].Name != name || result.Migrations[0].RowsAffected != 1 {
+		t.Errorf("expected only the last statement of '%s' to have been run, got %+v", name, result.Migrations)
+	}
+	checkDirty(t, Make(db, path), name, 0)
+	checkMigrated(t, path, map[string]bool{name: true})
+	conn := getConnection(t)
+	defer conn.Close()
+	var total int
+	if err = conn.QueryRow("SELECT COUNT(*) FROM gadgets").Scan(&total); err != nil || total != 2 {
+		t.Errorf("expected 2 gadgets, got %d (%v)", total, err)
+	}
+	if _, err = Make(db, path).Resume(); err == nil {
+		t.Errorf("expected an error resuming when no migration is dirty")
+	}
+}
+
+func TestDirtyTransaction(t *testing.T) {
+	conn := getConnection(t)
+	defer conn.Close()
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	name, err := writeMigration(path, "partial", 1, fmt.Sprintf(TEST_PARTIAL, "missing_table"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Make(db, path, WithConnection(conn)).MigrateUp(); err == nil {
+		t.Fatalf("expected the third statement to fail")
+	}
+	// The CREATE commits implicitly, ending the transaction, so the first INSERT
+	// is committed as well
+	checkDirty(t, Make(db, path), name, 2)
+	if count := countGadgets(t); count != 1 {
+		t.Errorf("expected the first insert to have been committed, found %d gadgets", count)
+	}
+
+	// Resume only runs the statement that failed
+	if _, err = writeMigration(path, "partial", 1, fmt.Sprintf(TEST_PARTIAL, "(SELECT 2 AS id, 'widget' AS name) AS fixed")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Make(db, path, WithConnection(conn)).Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if count := countGadgets(t); count != 2 {
+		t.Errorf("expected 2 gadgets after resuming, found %d", count)
+	}
+
+	if _, err = Make(db, path).Force(name, false); err != nil {
+		t.Fatal(err)
+	}
+	checkDirty(t, Make(db, path), name, 0)
+	checkMigrated(t, path, map[string]bool{name: false})
+	if _, err = Make(db, path).Force(name, true); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, path, map[string]bool{name: true})
+	if drifts, err := Make(db, path).Verify(); err != nil || len(drifts) > 0 {
+		t.Errorf("expected the forced migration to have its checksum recorded, got %v (%v)", drifts, err)
+	}
+}
+
+func TestDirtyBeforeImplicitCommit(t *testing.T) {
+	conn := getConnection(t)
+	defer conn.Close()
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	if _, err = writeMigration(path, "create_gadgets_table", 1, TEST_GADGETS_TABLE); err != nil {
+		t.Fatal(err)
+	}
+	name, err := writeMigration(path, "partial", 2, fmt.Sprintf(TEST_DML_THEN_DDL, "missing_table"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Make(db, path, WithConnection(conn)).MigrateUp(); err == nil {
+		t.Fatalf("expected the ALTER to fail")
+	}
+	// The ALTER commits the INSERT before it fails
+	checkDirty(t, Make(db, path), name, 1)
+	if _, err = Make(db, path).MigrateUp(); !errors.Is(err, ErrDirty) {
+		t.Errorf("expected ErrDirty while a migration is dirty, got %v", err)
+	}
+
+	// Resume does not run the INSERT again
+	if _, err = writeMigration(path, "partial", 2, fmt.Sprintf(TEST_DML_THEN_DDL, "gadgets")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Make(db, path, WithConnection(conn)).Resume(); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, path, map[string]bool{name: true})
+	if count := countGadgets(t); count != 1 {
+		t.Errorf("expected 1 gadget after resuming, found %d", count)
+	}
+}
+
+// checkDirty checks that the migration is dirty with the statements done, or
+// not dirty if none are expected
+func checkDirty(t *testing.T, m *Migration, name string, done int) {
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if status.Name != name {
+			continue
+		}
+		if status.Dirty != (done > 0) || status.Done != done {
+			t.Errorf("expected '%s' to have dirty = %t with %d statements done, got %+v", name, done > 0, done, status)
+		}
+		return
+	}
+	t.Errorf("migration '%s' not found", name)
+}
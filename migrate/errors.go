@@ -0,0 +1,111 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// ERROR_SNIPPET_LENGTH is the most SQL of the failing statement kept in a MigrationError
+	ERROR_SNIPPET_LENGTH = 200
+)
+
+// MigrationError is returned when a migration fails to run or be reversed. It
+// wraps the error returned by the database, so that errors.As also finds the
+// driver's error, such as a *mysql.MySQLError.
+type MigrationError struct {
+	Name        string
+	MigrationID int64
+	Direction   string // DIRECTION_UP or DIRECTION_DOWN
+	Statement   int    // the number of the statement that failed, from 1; zero if no statement did, as for Go migrations
+	StartLine   int    // the lines of the file that the statement spans; zero if they are not known
+	EndLine     int
+	SQL         string // the start of the statement
+	Number      uint16 // the MySQL error number; zero for other errors (see Dialect.ErrorNumber)
+	Err         error
+}
+
+func (e *MigrationError) Error() string {
+	if e.Statement < 1 {
+		return fmt.Sprintf("migration '%s' failed (%s): %s", e.Name, e.Direction, e.Err.Error())
+	}
+	lines := ""
+	if e.StartLine > 0 {
+		lines = fmt.Sprintf(", lines %d-%d", e.StartLine, e.EndLine)
+	}
+	return fmt.Sprintf("migration '%s' failed (%s, statement %d%s): %s; SQL: %s", e.Name, e.Direction, e.Statement, lines, e.Err.Error(), strings.Join(strings.Fields(e.SQL), " "))
+}
+
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
+
+// statementError describes the failure of a statement of the migration, which
+// is the index-th of its SQL
+func (m *Migration) statementError(file *migrationFile, statements []string, index int, err error) *MigrationError {
+	migrationErr := &MigrationError{Statement: index + 1, Err: err}
+	statement, _ := skipComments(statements[index])
+	if len(statement) > ERROR_SNIPPET_LENGTH {
+		migrationErr.SQL = statement[:ERROR_SNIPPET_LENGTH] + "..."
+	} else {
+		migrationErr.SQL = statement
+	}
+	migrationErr.StartLine, migrationErr.EndLine = m.statementLines(file, statements, index)
+	return migrationErr
+}
+
+// statementLines finds the lines of the file that the index-th statement spans,
+// looking for each statement in turn after the last. Statements that have been
+// rewritten when they were split, such as those with their own delimiter, may
+// not be found.
+func (m *Migration) statementLines(file *migrationFile, statements []string, index int) (start, end int) {
+	offset := 0
+	if !m.direction {
+		// The down SQL starts on the line of the direction marker
+		offset = strings.Count(file.up, "\n")
+	}
+	cursor := 0
+	for i := 0; i <= index; i++ {
+		statement := strings.TrimSpace(statements[i])
+		found := strings.Index(file.sql[cursor:], statement)
+		if found < 0 {
+			return 0, 0
+		}
+		cursor += found
+		if i < index {
+			cursor += len(statement)
+		}
+	}
+	statement, skipped := skipComments(statements[index])
+	start = offset + strings.Count(file.sql[:cursor], "\n") + skipped + 1
+	end = start + strings.Count(statement, "\n")
+	return
+}
+
+// skipComments trims the statement of the comments and blank lines before it,
+// returning how many lines were skipped
+func skipComments(statement string) (trimmed string, skipped int) {
+	lines := strings.Split(strings.TrimSpace(statement), "\n")
+	for skipped < len(lines)-1 {
+		line := strings.TrimSpace(lines[skipped])
+		if len(line) > 0 && !strings.HasPrefix(line, "--") && !strings.HasPrefix(line, "#") {
+			break
+		}
+		skipped++
+	}
+	return strings.TrimSpace(strings.Join(lines[skipped:], "\n")), skipped
+}
+
+// migrationError completes the error with the migration that failed
+func (m *Migration) migrationError(result *MigrationResult, err error) error {
+	var migrationErr *MigrationError
+	if !errors.As(err, &migrationErr) {
+		migrationErr = &MigrationError{Err: err}
+	}
+	migrationErr.Name = result.Name
+	migrationErr.MigrationID = result.MigrationID
+	migrationErr.Direction = result.Direction
+	migrationErr.Number = m.dialect.ErrorNumber(err)
+	return migrationErr
+}
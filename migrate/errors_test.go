@@ -0,0 +1,71 @@
+package migrate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const TEST_MISSING_TABLE = `-- add your UP SQL here
+
+CREATE TABLE gadgets (id INT PRIMARY KEY);
+INSERT INTO gadgets (id)
+SELECT id FROM missing_table;
+
+-- [DIRECTION] -- do not alter this line!
+-- add your DOWN SQL here
+
+DROP TABLE gadgets;
+`
+
+func TestMigrationError(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	name, err := writeMigration(path, "insert_missing", 1, TEST_MISSING_TABLE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Make(db, path).MigrateUp()
+	var migrationErr *MigrationError
+	if !errors.As(err, &migrationErr) {
+		t.Fatalf("expected a *MigrationError, got %v", err)
+	}
+	if migrationErr.Name != name || migrationErr.MigrationID != 1 || migrationErr.Direction != DIRECTION_UP {
+		t.Errorf("expected the error to name the migration, got %+v", migrationErr)
+	}
+	if migrationErr.Statement != 2 || migrationErr.StartLine != 4 || migrationErr.EndLine != 5 {
+		t.Errorf("expected the second statement, on lines 4-5, to have failed, got statement %d on lines %d-%d", migrationErr.Statement, migrationErr.StartLine, migrationErr.EndLine)
+	}
+	if !strings.HasPrefix(migrationErr.SQL, "INSERT INTO gadgets") {
+		t.Errorf("expected the failing SQL, got '%s'", migrationErr.SQL)
+	}
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) || migrationErr.Number != mysqlErr.Number || migrationErr.Number == 0 {
+		t.Errorf("expected the MySQL error to be wrapped, got %v", err)
+	}
+}
+
+func TestStatementLines(t *testing.T) {
+	up, down, err := parseMigration("", TEST_MISSING_TABLE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := New(FromDB(nil), MakeMemorySource())
+	file := &migrationFile{name: "insert_missing.1", up: up, down: down, sql: down}
+	m.direction = false
+	statements := getStatements(m.dialect, file.sql)
+	migrationErr := m.statementError(file, statements, 0, errors.New("failed"))
+	if migrationErr.StartLine != 10 || migrationErr.EndLine != 10 {
+		t.Errorf("expected the down statement to be on line 10, got lines %d-%d", migrationErr.StartLine, migrationErr.EndLine)
+	}
+	migrationErr.Name, migrationErr.Direction = file.name, DIRECTION_DOWN
+	expected := "migration 'insert_missing.1' failed (down, statement 1, lines 10-10): failed; SQL: DROP TABLE gadgets"
+	if migrationErr.Error() != expected {
+		t.Errorf("expected '%s', got '%s'", expected, migrationErr.Error())
+	}
+}
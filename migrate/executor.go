@@ -0,0 +1,226 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/blainemoser/MySqlDB/database"
+)
+
+const (
+	SCHEMA_QUERY = "SELECT DATABASE() AS name"
+)
+
+type (
+	// Executor runs the queries of the migrations and keeps the migrations table
+	Executor interface {
+		Execer
+		// CheckHasTable reports whether the table exists in the current schema
+		CheckHasTable(table string) (bool, error)
+		// CreateRecord inserts a row into the table, whose name is quoted and may be
+		// qualified by its schema, and returns its id
+		CreateRecord(table string, properties map[string]interface{}) (int64, error)
+		// UpdateRecord updates the row of the table, named as for CreateRecord,
+		// identified by the key property
+		UpdateRecord(table, key string, properties map[string]interface{}) error
+	}
+
+	// databaseExecutor adapts a MySqlDB database
+	databaseExecutor struct {
+		*database.Database
+	}
+
+	// sqlExecutor adapts a *sql.DB, *sql.Conn or *sql.Tx
+	sqlExecutor struct {
+		runner  sqlRunner
+		db      *sql.DB   // set when the executor can pin a connection from a pool
+		conn    *sql.Conn // set when the executor is a single connection
+		dialect Dialect   // set by New; see WithDialect
+	}
+
+	// sqlRunner is satisfied by *sql.DB, *sql.Conn and *sql.Tx
+	sqlRunner interface {
+		ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+		QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+		QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	}
+)
+
+// FromDatabase adapts a MySqlDB database for New. It only supports the MySQL dialect.
+func FromDatabase(db *database.Database) Executor {
+	return &databaseExecutor{db}
+}
+
+// FromDB adapts a connection pool for New. Each migration is run in a transaction
+// and migrations are locked (see WithLockTimeout) on a connection from the pool.
+func FromDB(db *sql.DB) Executor {
+	return &sqlExecutor{runner: db, db: db}
+}
+
+// FromConn adapts a single connection for New. Each migration is run in a
+// transaction and migrations are locked on the connection.
+func FromConn(conn *sql.Conn) Executor {
+	return &sqlExecutor{runner: conn, conn: conn}
+}
+
+// FromTx adapts a transaction for New. The migrations are run in the transaction,
+// which the caller commits or rolls back.
+func FromTx(tx *sql.Tx) Executor {
+	return &sqlExecutor{runner: tx}
+}
+
+func (d *databaseExecutor) MySqlDB() *database.Database {
+	return d.Database
+}
+
+func (d *databaseExecutor) CreateRecord(table string, properties map[string]interface{}) (int64, error) {
+	fields, inserts := recordFields(properties, "")
+	result, err := d.Exec(insertQuery(table, fields), inserts)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (d *databaseExecutor) UpdateRecord(table, key string, properties map[string]interface{}) error {
+	fields, inserts := recordFields(properties, key)
+	_, err := d.Exec(updateQuery(table, key, fields), append(inserts, properties[key]))
+	return err
+}
+
+func (e *sqlExecutor) Exec(query string, inserts []interface{}) (sql.Result, error) {
+	return e.runner.ExecContext(context.Background(), query, inserts...)
+}
+
+// QueryRaw returns the rows as maps of column names to values. Integer columns
+// are returned as int64 and other columns returned as bytes by the driver are
+// returned as strings.
+func (e *sqlExecutor) QueryRaw(query string, inserts []interface{}) ([]map[string]interface{}, error) {
+	rows, err := e.runner.QueryContext(context.Background(), query, inserts...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		values := make([]interface{}, len(cols))
+		pointers := make([]interface{}, len(cols))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err = rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{})
+		for i, col := range cols {
+			row[col.Name()] = getValue(col, values[i])
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+func (e *sqlExecutor) CheckHasTable(table string) (bool, error) {
+	var total int64
+	query := e.getDialect().Bind(e.getDialect().TableQuery())
+	if err := e.runner.QueryRowContext(context.Background(), query, "", table).Scan(&total); err != nil {
+		return false, err
+	}
+	return total > 0, nil
+}
+
+func (e *sqlExecutor) CreateRecord(table string, properties map[string]interface{}) (int64, error) {
+	fields, inserts := recordFields(properties, "")
+	query, returning := e.getDialect().InsertQuery(table, fields)
+	if returning {
+		var id int64
+		err := e.runner.QueryRowContext(context.Background(), query, inserts...).Scan(&id)
+		return id, err
+	}
+	result, err := e.Exec(query, inserts)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (e *sqlExecutor) UpdateRecord(table, key string, properties map[string]interface{}) error {
+	fields, inserts := recordFields(properties, key)
+	_, err := e.Exec(e.getDialect().UpdateQuery(table, key, fields), append(inserts, properties[key]))
+	return err
+}
+
+func (e *sqlExecutor) getDialect() Dialect {
+	if e.dialect == nil {
+		return MySQL
+	}
+	return e.dialect
+}
+
+// transactional reports whether the executor can begin transactions; a
+// transaction cannot
+func (e *sqlExecutor) transactional() bool {
+	return e.db != nil || e.conn != nil
+}
+
+func (e *sqlExecutor) begin() (*sql.Tx, error) {
+	if e.db != nil {
+		return e.db.Begin()
+	}
+	if e.conn != nil {
+		return e.conn.BeginTx(context.Background(), nil)
+	}
+	return nil, errors.New("cannot begin a transaction within a transaction")
+}
+
+// session returns a runner that keeps the same session until it is released, as
+// needed by the migration lock
+func (e *sqlExecutor) session(ctx context.Context) (sqlRunner, func() error, error) {
+	if e.db == nil {
+		return e.runner, func() error { return nil }, nil
+	}
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conn, conn.Close, nil
+}
+
+// recordFields returns the fields of the record other than the key, in order, and
+// their values
+func recordFields(properties map[string]interface{}, key string) (fields []string, inserts []interface{}) {
+	fields = make([]string, 0, len(properties))
+	for field := range properties {
+		if field != key {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	inserts = make([]interface{}, 0, len(fields))
+	for _, field := range fields {
+		inserts = append(inserts, properties[field])
+	}
+	return fields, inserts
+}
+
+// getValue converts a value scanned by the driver
+func getValue(col *sql.ColumnType, value interface{}) interface{} {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return value
+	}
+	if strings.Contains(strings.ToUpper(col.DatabaseTypeName()), "INT") {
+		if parsed, err := strconv.ParseInt(string(bytes), 10, 64); err == nil {
+			return parsed
+		}
+	}
+	return string(bytes)
+}
@@ -0,0 +1,78 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+)
+
+func TestExecutors(t *testing.T) {
+	conn := getConnection(t)
+	defer conn.Close()
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	names := seedGadgets(t, path)
+	single, err := conn.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer single.Close()
+	cases := map[string]func() (Executor, func() error){
+		"db": func() (Executor, func() error) {
+			return FromDB(conn), func() error { return nil }
+		},
+		"conn": func() (Executor, func() error) {
+			return FromConn(single), func() error { return nil }
+		},
+		"tx": func() (Executor, func() error) {
+			tx, err := conn.Begin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			return FromTx(tx), tx.Commit
+		},
+	}
+	for name, executor := range cases {
+		for _, up := range []bool{true, false} {
+			exec, done := executor()
+			m := New(exec, MakeFileSource(path))
+			if up {
+				_, err = m.MigrateUp()
+			} else {
+				_, err = m.MigrateDown()
+			}
+			if err != nil {
+				t.Fatalf("%s: %s", name, err.Error())
+			}
+			if err = done(); err != nil {
+				t.Fatal(err)
+			}
+			checkMigrated(t, path, map[string]bool{names[0]: up, names[1]: up, names[2]: up})
+		}
+	}
+}
+
+func TestSQLExecutorQueryRaw(t *testing.T) {
+	conn := getConnection(t)
+	defer conn.Close()
+	exec := FromDB(conn)
+	rows, err := exec.QueryRaw("SELECT 1 AS one, 'two' AS two", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if one, ok := rows[0]["one"].(int64); !ok || one != 1 {
+		t.Errorf("expected an int64, got %#v", rows[0]["one"])
+	}
+	if two, ok := rows[0]["two"].(string); !ok || two != "two" {
+		t.Errorf("expected a string, got %#v", rows[0]["two"])
+	}
+	hasTable, err := exec.CheckHasTable("no_such_table")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasTable {
+		t.Errorf("expected the table not to exist")
+	}
+}
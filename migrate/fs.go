@@ -0,0 +1,25 @@
+package migrate
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/blainemoser/MySqlDB/database"
+)
+
+// ErrReadOnly is returned by Create when the source of the migrations cannot be
+// written to, such as an fs.FS
+var ErrReadOnly = errors.New("the migrations source is read-only")
+
+// MakeFS creates a new migration whose files are read from the root of fsys,
+// such as an embed.FS, so that migrations can be compiled into the binary. Use
+// fs.Sub for migrations kept in a subdirectory:
+//
+//	//go:embed migrations/*.sql
+//	var embedded embed.FS
+//
+//	migrations, _ := fs.Sub(embedded, "migrations")
+//	m := migrate.MakeFS(&db, migrations)
+func MakeFS(database *database.Database, fsys fs.FS, options ...Option) *Migration {
+	return New(FromDatabase(database), MakeFSSource(fsys), options...)
+}
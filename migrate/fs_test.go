@@ -0,0 +1,42 @@
+package migrate
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMakeFS(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	fsys := fstest.MapFS{
+		"create_gadgets_table.1.sql":     {Data: []byte(TEST_GADGETS_TABLE)},
+		"alter_gadgets_add_colour.2.sql": {Data: []byte(TEST_ALTER_GADGETS)},
+		"README.md":                      {Data: []byte("not a migration")},
+	}
+	if err = MakeFS(db, fsys).Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = MakeFS(db, fsys).MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, path, map[string]bool{"create_gadgets_table.1": true, "alter_gadgets_add_colour.2": true})
+	drifts, err := MakeFS(db, fsys).Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifts) > 0 {
+		t.Errorf("expected no drift, got %v", drifts)
+	}
+	if _, err = MakeFS(db, fsys).MigrateDown(); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, path, map[string]bool{"create_gadgets_table.1": false, "alter_gadgets_add_colour.2": false})
+
+	if _, _, _, err = MakeFS(db, fsys).Create("read_only"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly when creating a migration, got %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package migrate
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/blainemoser/MySqlDB/database"
+)
+
+type (
+	// Execer runs queries for a Go migration. It is satisfied by every Executor,
+	// including the transaction a migration runs in when there is one.
+	Execer interface {
+		Exec(query string, inserts []interface{}) (sql.Result, error)
+		QueryRaw(query string, inserts []interface{}) ([]map[string]interface{}, error)
+	}
+
+	// DatabaseExecer is the Execer that Go migrations receive from a MySqlDB
+	// database (see FromDatabase and Make) when they are not run in a transaction.
+	// Assert it to reach the rest of the database's methods.
+	DatabaseExecer interface {
+		Execer
+		// MySqlDB returns the database that the queries are run on
+		MySqlDB() *database.Database
+	}
+
+	// MigrationFunc is a migration, or its reversal, written in Go
+	MigrationFunc func(db Execer) error
+
+	funcMigration struct {
+		timestamp int64
+		up        MigrationFunc
+		down      MigrationFunc
+	}
+)
+
+// Register adds a migration written in Go. It is ordered by its timestamp among
+// the migration files and is recorded in the migrations table under the name
+// {name}.{timestamp}, exactly as a file would be. Either function may be nil, in
+// which case that direction does nothing but update the record. The functions
+// run in the same transaction as the update of the record when there is a
+// connection (see WithConnection).
+func (m *Migration) Register(name string, timestamp int64, up, down MigrationFunc) error {
+	if len(name) < 1 || strings.ContainsAny(name, "./") {
+		return fmt.Errorf("invalid migration name '%s'; it should not be empty or contain '.' or '/'", name)
+	}
+	if timestamp < 1 {
+		return fmt.Errorf("invalid timestamp %d for migration '%s'", timestamp, name)
+	}
+	fullname := fmt.Sprintf("%s.%d", name, timestamp)
+	for registered, fn := range m.funcs {
+		if fn.timestamp == timestamp {
+			return fmt.Errorf("migration '%s' has the same timestamp as '%s'", fullname, registered)
+		}
+	}
+	m.funcs[fullname] = &funcMigration{timestamp: timestamp, up: up, down: down}
+	return nil
+}
+
+// addFuncs adds the registered Go migrations to the files found on disk
+func (m *Migration) addFuncs(files map[int]string, keys *[]int) error {
+	errs := make([]error, 0)
+	for name, fn := range m.funcs {
+		key := int(fn.timestamp)
+		if file, ok := files[key]; ok {
+			errs = append(errs, fmt.Errorf("migration '%s' has the same timestamp as the file '%s'", name, file))
+			continue
+		}
+		files[key] = name
+		*keys = append(*keys, key)
+	}
+	return GetErrors(errs)
+}
+
+// file returns the migration for the direction
+func (f *funcMigration) file(name string, direction bool) *migrationFile {
+	file := &migrationFile{name: name, isFunc: true, fn: f.down}
+	if direction {
+		file.fn = f.up
+	}
+	return file
+}
+
+// run executes the statements of the migration after the first skip, or its Go
+// function, and returns the number of rows its statements affected, where the
+// driver reports it. Progress is called with the number of statements done after
+// each one succeeds, and the statement that comes next, if any.
+func (m *Migration) run(file *migrationFile, db Execer, skip int, progress func(done int, statement, next string) error) (rows int64, err error) {
+	if file.isFunc {
+		if file.fn == nil {
+			return 0, nil
+		}
+		return 0, file.fn(db)
+	}
+	statements := getStatements(m.dialect, file.sql)
+	for i, statement := range statements {
+		if i < skip {
+			continue
+		}
+		result, err := db.Exec(statement, nil)
+		if err != nil {
+			return rows, m.statementError(file, statements, i, err)
+		}
+		if result != nil {
+			if affected, err := result.RowsAffected(); err == nil {
+				rows += affected
+			}
+		}
+		next := ""
+		if i+1 < len(statements) {
+			next = statements[i+1]
+		}
+		if err = progress(i+1, statement, next); err != nil {
+			return rows, err
+		}
+	}
+	return rows, nil
+}
@@ -0,0 +1,167 @@
+package migrate
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGoMigrations(t *testing.T) {
+	conn := getConnection(t)
+	defer conn.Close()
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	names := make([]string, 0)
+	for i, mig := range []struct{ name, content string }{
+		{"create_gadgets_table", TEST_GADGETS_TABLE},
+		{"alter_gadgets_add_colour", TEST_ALTER_GADGETS},
+		{"alter_gadgets_add_weight", TEST_ALTER_GADGETS_AGAIN},
+	} {
+		// Leave gaps between the timestamps for the Go migration
+		name, err := writeMigration(path, mig.name, int64(i*2+1), mig.content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	for _, options := range [][]Option{nil, {WithConnection(conn)}} {
+		m := Make(db, path, options...)
+		registerGadgetBackfill(t, m)
+		if _, err = m.MigrateUp(); err != nil {
+			t.Fatal(err)
+		}
+		checkMigrated(t, path, map[string]bool{names[0]: true, names[1]: true, "backfill_gadgets.4": true, names[2]: true})
+		if count := countGadgets(t); count != 2 {
+			t.Errorf("expected the Go migration to insert 2 gadgets, found %d", count)
+		}
+		if _, err = m.MigrateDown(); err != nil {
+			t.Fatal(err)
+		}
+		checkMigrated(t, path, map[string]bool{names[0]: false, names[1]: false, "backfill_gadgets.4": false, names[2]: false})
+	}
+}
+
+func TestGoMigrationRollback(t *testing.T) {
+	conn := getConnection(t)
+	defer conn.Close()
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	created, err := writeMigration(path, "create_gadgets_table", 1, TEST_GADGETS_TABLE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := Make(db, path, WithConnection(conn))
+	err = m.Register("failing_backfill", 2, func(db Execer) error {
+		if _, err := db.Exec("INSERT INTO gadgets (name) VALUES (?)", []interface{}{"sprocket"}); err != nil {
+			return err
+		}
+		return errors.New("backfill failed")
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.MigrateUp(); err == nil {
+		t.Fatalf("expected the failing Go migration to return an error")
+	}
+	checkMigrated(t, path, map[string]bool{created: true, "failing_backfill.2": false})
+	if count := countGadgets(t); count != 0 {
+		t.Errorf("expected the insert to have been rolled back, found %d gadgets", count)
+	}
+}
+
+func TestGoMigrationDatabase(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	if _, err = writeMigration(path, "create_gadgets_table", 1, TEST_GADGETS_TABLE); err != nil {
+		t.Fatal(err)
+	}
+	m := Make(db, path)
+	var queried, total int64
+	err = m.Register("count_gadgets", 2, func(exec Execer) error {
+		rows, err := exec.QueryRaw("SELECT COUNT(*) AS total FROM gadgets", nil)
+		if err != nil {
+			return err
+		}
+		queried, _ = rows[0]["total"].(int64)
+		mysqlDB, ok := exec.(DatabaseExecer)
+		if !ok {
+			return errors.New("expected the Execer to expose the MySqlDB database")
+		}
+		if _, err = mysqlDB.MySqlDB().Exec("INSERT INTO gadgets (name) VALUES (?)", []interface{}{"sprocket"}); err != nil {
+			return err
+		}
+		if rows, err = mysqlDB.MySqlDB().QueryRaw("SELECT COUNT(*) AS total FROM gadgets", nil); err != nil {
+			return err
+		}
+		total, _ = rows[0]["total"].(int64)
+		return nil
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	if queried != 0 || total != 1 {
+		t.Errorf("expected the Go migration to count 0 gadgets and then 1, got %d and %d", queried, total)
+	}
+}
+
+func TestRegisterErrors(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reset()
+	m := Make(db, path)
+	for _, name := range []string{"", "has.dot", "has/slash"} {
+		if err = m.Register(name, 1, nil, nil); err == nil {
+			t.Errorf("expected an error for the name '%s'", name)
+		}
+	}
+	if err = m.Register("first", 5, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Register("second", 5, nil, nil); err == nil {
+		t.Errorf("expected an error for a duplicate timestamp")
+	}
+	if _, err = writeMigration(path, "clashing", 5, MIGRATE_DEFAULT); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Validate(); err == nil {
+		t.Errorf("expected an error for a Go migration with the same timestamp as a file")
+	}
+}
+
+func registerGadgetBackfill(t *testing.T, m *Migration) {
+	up := func(db Execer) error {
+		for _, name := range []string{"sprocket", "widget"} {
+			if _, err := db.Exec("INSERT INTO gadgets (name, colour) VALUES (?, ?)", []interface{}{name, "red"}); err != nil {
+				return err
+			}
+		}
+		rows, err := db.QueryRaw("SELECT COUNT(*) AS total FROM gadgets WHERE colour = ?", []interface{}{"red"})
+		if err != nil {
+			return err
+		}
+		if len(rows) != 1 {
+			return errors.New("expected a single row")
+		}
+		return nil
+	}
+	down := func(db Execer) error {
+		_, err := db.Exec("DELETE FROM gadgets", nil)
+		return err
+	}
+	if err := m.Register("backfill_gadgets", 4, up, down); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,68 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"time"
+)
+
+const (
+	DEFAULT_LOCK_TIMEOUT = time.Minute
+	LOCK_QUERY           = "SELECT GET_LOCK(?, ?) AS locked"
+	UNLOCK_QUERY         = "SELECT RELEASE_LOCK(?) AS released"
+)
+
+// ErrLocked is returned when another process holds the migration lock and it
+// could not be acquired in time
+var ErrLocked = errors.New("migrations are locked by another process")
+
+// withLock runs the function while holding the lock of the dialect, such as a
+// MySQL named lock, so that processes sharing the database cannot run migrations
+// at the same time. The lock needs a dedicated session, so it is only taken when
+// a connection has been supplied (see WithConnection); without one a warning
+// is logged. It is released when the function returns or panics.
+func (m *Migration) withLock(run func() error) (err error) {
+	if m.dryRun {
+		return run()
+	}
+	if m.conn == nil {
+		log.Println("warning: migrations are being run without a lock, so other processes sharing the database may run them at the same time; supply the connection pool with WithConnection")
+		return run()
+	}
+	release, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if releaseErr := release(); releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+	}()
+	return run()
+}
+
+func (m *Migration) lock() (func() error, error) {
+	conn, release, err := m.conn.session(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := m.dialect.Lock(&sqlExecutor{runner: conn, dialect: m.dialect}, m.tableSchema, m.tableName, m.lockTimeout)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return func() error {
+		defer release()
+		return unlock()
+	}, nil
+}
+
+// lockSeconds rounds the timeout up to whole seconds, as expected by GET_LOCK
+func lockSeconds(timeout time.Duration) int64 {
+	if timeout <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(timeout.Seconds()))
+}
@@ -0,0 +1,102 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLock(t *testing.T) {
+	conn := getConnection(t)
+	defer conn.Close()
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	names := seedGadgets(t, path)
+
+	// Another process holds the lock
+	m := Make(db, path, WithConnection(conn), WithLockFailFast())
+	name, err := lockName(FromDB(conn), "", DEFAULT_TABLE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	holder, err := conn.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = holder.ExecContext(context.Background(), "SELECT GET_LOCK(?, 0)", name); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.MigrateUp(); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked, got %v", err)
+	}
+	started := time.Now()
+	if _, err = Make(db, path, WithConnection(conn), WithLockTimeout(time.Second)).MigrateUp(); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked after waiting, got %v", err)
+	}
+	if time.Since(started) < time.Second {
+		t.Errorf("expected to wait for the lock")
+	}
+	checkMigrated(t, path, map[string]bool{names[0]: false})
+	if _, err = holder.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", name); err != nil {
+		t.Fatal(err)
+	}
+	holder.Close()
+
+	if _, err = m.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, path, map[string]bool{names[0]: true, names[1]: true, names[2]: true})
+
+	// The lock is released after the run
+	release, err := m.lock()
+	if err != nil {
+		t.Fatalf("expected the lock to have been released, got %v", err)
+	}
+	if err = release(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLockWarning(t *testing.T) {
+	conn := getConnection(t)
+	defer conn.Close()
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	seedGadgets(t, path)
+	output := captureLog(t, func() error {
+		_, err := Make(db, path, WithConnection(conn)).MigrateUp()
+		return err
+	})
+	if strings.Contains(output, "without a lock") {
+		t.Errorf("did not expect a warning with a connection, got '%s'", output)
+	}
+	output = captureLog(t, func() error {
+		_, err := Make(db, path).MigrateDown()
+		return err
+	})
+	if !strings.Contains(output, "warning: migrations are being run without a lock") {
+		t.Errorf("expected a warning without a connection, got '%s'", output)
+	}
+}
+
+// captureLog returns what is logged while the function runs
+func captureLog(t *testing.T, run func() error) string {
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+	if err := run(); err != nil {
+		t.Fatal(err)
+	}
+	return output.String()
+}
@@ -0,0 +1,91 @@
+package migrate
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	DIRECTION_UP   = "up"
+	DIRECTION_DOWN = "down"
+)
+
+// runMetadata describes the run, and is recorded with each migration that is
+// run or reversed in it. Values that cannot be found are recorded as NULL.
+func (m *Migration) runMetadata() (map[string]interface{}, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		log.Printf("warning: could not get the hostname to record with the migrations: %s\n", err.Error())
+	}
+	user, err := m.databaseUser()
+	if err != nil {
+		return nil, err
+	}
+	direction := DIRECTION_DOWN
+	if m.direction {
+		direction = DIRECTION_UP
+	}
+	return map[string]interface{}{
+		"host":        nullable(host),
+		"db_user":     nullable(user),
+		"app_version": nullable(m.appVersion),
+		"direction":   direction,
+	}, nil
+}
+
+// databaseUser returns the user that the migrations are run as
+func (m *Migration) databaseUser() (string, error) {
+	query := m.dialect.UserQuery()
+	if len(query) < 1 {
+		return "", nil
+	}
+	rows, err := m.query(query, nil)
+	if err != nil || len(rows) < 1 {
+		return "", err
+	}
+	return getString(rows[0]["name"]), nil
+}
+
+// runTimed runs the migration (see run), recording how long it took in its
+// record and result, together with the rows it affected
+func (m *Migration) runTimed(file *migrationFile, db Execer, properties map[string]interface{}, result *MigrationResult, skip int, transactional bool) error {
+	start := time.Now()
+	rows, err := m.run(file, db, skip, m.progress(result, transactional))
+	if err != nil {
+		return err
+	}
+	result.Duration = time.Since(start)
+	result.RowsAffected = rows
+	properties["duration_ms"] = result.Duration.Milliseconds()
+	return nil
+}
+
+func nullable(value string) interface{} {
+	if len(value) < 1 {
+		return nil
+	}
+	return value
+}
+
+func getDuration(duration interface{}) (time.Duration, error) {
+	var ms int64
+	var err error
+	switch value := duration.(type) {
+	case nil:
+		return 0, nil
+	case int64:
+		ms = value
+	case int:
+		ms = int64(value)
+	case string:
+		ms, err = strconv.ParseInt(value, 10, 64)
+	case []byte:
+		ms, err = strconv.ParseInt(string(value), 10, 64)
+	default:
+		err = fmt.Errorf("duration is not an integer")
+	}
+	return time.Duration(ms) * time.Millisecond, err
+}
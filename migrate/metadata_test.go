@@ -0,0 +1,50 @@
+package migrate
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMetadata(t *testing.T) {
+	conn := getConnection(t)
+	defer conn.Close()
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	names := seedGadgets(t, path)
+	host, _ := os.Hostname()
+	for _, options := range [][]Option{
+		{WithAppVersion("v1.2.3")},
+		{WithAppVersion("v1.2.4"), WithConnection(conn)},
+	} {
+		m := Make(db, path, options...)
+		if _, err = m.MigrateUp(); err != nil {
+			t.Fatal(err)
+		}
+		checkMetadata(t, m, len(names), DIRECTION_UP, host)
+		if _, err = m.MigrateDown(); err != nil {
+			t.Fatal(err)
+		}
+		checkMetadata(t, m, len(names), DIRECTION_DOWN, host)
+	}
+}
+
+func checkMetadata(t *testing.T, m *Migration, expected int, direction, host string) {
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != expected {
+		t.Errorf("expected %d migrations, got %d", expected, len(statuses))
+	}
+	for _, status := range statuses {
+		if status.Direction != direction || status.Host != host || status.AppVersion != m.appVersion {
+			t.Errorf("expected '%s' to have been run %s on '%s' by version '%s', got %+v", status.Name, direction, host, m.appVersion, status)
+		}
+		if len(status.DBUser) < 1 || status.Duration < 0 {
+			t.Errorf("expected the user and duration to have been recorded for '%s', got %+v", status.Name, status)
+		}
+	}
+}
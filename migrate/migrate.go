@@ -1,18 +1,18 @@
 package migrate
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
-	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/blainemoser/MySqlDB/database"
-	_ "github.com/go-sql-driver/mysql"
 )
 
 const (
@@ -21,17 +21,25 @@ const (
 
 	MIGS_QUERY = `
 	SELECT migration_id, name 
-	FROM migrations 
-	WHERE migrations.migrated = ? [batch] 
+	FROM [table] 
+	WHERE migrated = ? [batch] 
 	ORDER BY migration_id [order];
 `
 
-	MIGS_TABLE = `CREATE TABLE migrations (
+	MIGS_TABLE = `CREATE TABLE [table] (
 	id INT(6) UNSIGNED AUTO_INCREMENT PRIMARY KEY,
 	migration_id BIGINT UNSIGNED,
 	batch_id BIGINT UNSIGNED,
 	name VARCHAR(1000) NOT NULL,
 	migrated TINYINT,
+	checksum VARCHAR(64) NULL,
+	duration_ms BIGINT NULL,
+	host VARCHAR(255) NULL,
+	db_user VARCHAR(255) NULL,
+	app_version VARCHAR(255) NULL,
+	direction VARCHAR(4) NULL,
+	dirty SMALLINT NULL,
+	statements_done INT NULL,
 	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
 )`
@@ -41,10 +49,14 @@ const (
 -- add your DOWN SQL here
 
 `
-	LAST_BATCH_QUERY = "SELECT batch_id FROM migrations where migrated = 1 ORDER BY migration_id DESC LIMIT 1"
-	EXISTS_QUERY     = "SELECT count(*) as taken FROM migrations WHERE name = ?;"
-	PERM             = 0700 // this is to give the caller full rights, but no other user or group.
-	REMOVE_FILE      = `DELETE FROM migrations WHERE migrations.name = ?`
+	LAST_BATCH_QUERY  = "SELECT MAX(batch_id) AS batch_id FROM [table] WHERE migrated = 1"
+	MAX_BATCH_QUERY   = "SELECT MAX(batch_id) AS batch_id FROM [table]"
+	EXISTS_QUERY      = "SELECT count(*) as taken FROM [table] WHERE name = ?;"
+	STATEMENT_MARKER  = "[STATEMENT]"
+	PERM              = 0700 // this is to give the caller full rights, but no other user or group.
+	REMOVE_FILE       = `DELETE FROM [table] WHERE name = ?`
+	DEFAULT_TABLE     = "migrations"
+	TABLE_PLACEHOLDER = "[table]"
 )
 
 var (
@@ -55,72 +67,177 @@ type (
 	// Migration is a migration
 	Migration struct {
 		direction           bool
-		migrations          map[int]string
-		database            *database.Database
-		path                string
+		steps               int
+		dryRun              bool
+		hasTable            bool
+		migrations          map[int]*migrationFile
+		funcs               map[string]*funcMigration
+		conn                *sqlExecutor // runs transactions and holds the lock; nil without a connection
+		dialect             Dialect
+		lockTimeout         time.Duration
+		strict              bool
+		schemaPath          string // see WithSchemaDump
+		appVersion          string // see WithAppVersion
+		missingFiles        MissingFilePolicy
+		tableName           string // see WithTable
+		tableSchema         string // see WithTableSchema; empty for the current schema
+		database            Executor
+		source              Source
 		files               []string
 		migrationCandidates []map[string]interface{}
 		fileFailures        []string
+		seeded              []map[string]interface{}
+		plan                Plan
+	}
+	// migrationFile is a migration that has been read from the source or registered in Go
+	migrationFile struct {
+		name   string
+		up     string
+		down   string
+		sql    string        // the SQL for the current direction
+		isFunc bool          // the migration is registered in Go rather than read from disk
+		fn     MigrationFunc // the Go function for the current direction
 	}
 	fileNotFound struct {
-		database *database.Database
-		message  string
-		file     string
+		message string
+		file    string
 	}
 )
 
-// Make creates a new migration
-func Make(database *database.Database, path string) *Migration {
-	return &Migration{
+// Make creates a new migration for the migration files in the directory at the path
+func Make(database *database.Database, path string, options ...Option) *Migration {
+	return New(FromDatabase(database), MakeFileSource(path), options...)
+}
+
+// MakeWithSource creates a new migration for the migrations provided by the source
+func MakeWithSource(database *database.Database, source Source, options ...Option) *Migration {
+	return New(FromDatabase(database), source, options...)
+}
+
+// New creates a new migration that runs the migrations provided by the source
+// with the executor. Use FromDB, FromConn or FromTx to adapt database/sql types.
+func New(exec Executor, source Source, options ...Option) *Migration {
+	m := &Migration{
 		direction:           true,
-		database:            database,
-		path:                path,
+		database:            exec,
+		source:              source,
 		files:               make([]string, 0),
 		migrationCandidates: make([]map[string]interface{}, 0),
-		migrations:          make(map[int]string),
+		migrations:          make(map[int]*migrationFile),
+		funcs:               make(map[string]*funcMigration),
 		fileFailures:        make([]string, 0),
+		seeded:              make([]map[string]interface{}, 0),
+		plan:                make(Plan, 0),
+		lockTimeout:         DEFAULT_LOCK_TIMEOUT,
+		missingFiles:        MISSING_FILES_WARN,
+		dialect:             MySQL,
+		tableName:           DEFAULT_TABLE,
+	}
+	if conn, ok := exec.(*sqlExecutor); ok {
+		m.conn = conn
 	}
+	for _, option := range options {
+		option(m)
+	}
+	if m.conn != nil {
+		m.conn.dialect = m.dialect
+	}
+	return m
 }
 
-func (m *Migration) MigrateUp() (string, error) {
+// MigrateUp runs every pending migration, oldest first, as a new batch
+func (m *Migration) MigrateUp() (Result, error) {
 	m.direction = true
 	return m.migrate()
 }
 
-func (m *Migration) MigrateDown() (string, error) {
+// MigrateDown reverses the migrations in the last batch, newest first
+func (m *Migration) MigrateDown() (Result, error) {
 	m.direction = false
 	return m.migrate()
 }
 
-func (m *Migration) migrate() (string, error) {
-	err := m.bootstrap()
-	if err != nil {
-		return "", err
+// MigrateUpSteps runs at most the next n pending migrations
+func (m *Migration) MigrateUpSteps(n int) (Result, error) {
+	m.direction = true
+	return m.migrateSteps(n)
+}
+
+// MigrateDownSteps reverses at most the last n migrations that were run,
+// regardless of the batch they were run in
+func (m *Migration) MigrateDownSteps(n int) (Result, error) {
+	m.direction = false
+	return m.migrateSteps(n)
+}
+
+func (m *Migration) migrateSteps(n int) (Result, error) {
+	if n < 1 {
+		return Result{}, fmt.Errorf("the number of steps should be at least 1, got %d", n)
 	}
-	return m.runMigrations()
+	m.steps = n
+	defer func() {
+		m.steps = 0
+	}()
+	return m.migrate()
+}
+
+func (m *Migration) migrate() (result Result, err error) {
+	result.DryRun = m.dryRun
+	err = m.withLock(func() error {
+		if err := m.prepare(); err != nil {
+			return err
+		}
+		if err := m.checkDirty(); err != nil {
+			return err
+		}
+		if err := m.handleMissing(); err != nil {
+			return err
+		}
+		if m.direction {
+			if err := m.checkDrift(); err != nil {
+				return err
+			}
+		}
+		if err := m.getMigrationsSQL(); err != nil {
+			return err
+		}
+		if result.Migrations, err = m.runMigrations(); err != nil {
+			return err
+		}
+		return m.writeSchema()
+	})
+	return
 }
 
-func (m *Migration) runMigrations() (message string, err error) {
-	batchID := time.Time.Unix(time.Now())
-	var sql string
-	var properties map[string]interface{}
-	var msg string
-	messages := make([]string, 0)
+func (m *Migration) runMigrations() (results []MigrationResult, err error) {
+	batchID, err := m.nextBatch()
+	if err != nil {
+		return
+	}
+	var file *migrationFile
+	var metadata map[string]interface{}
+	if !m.dryRun {
+		if metadata, err = m.runMetadata(); err != nil {
+			return
+		}
+	}
+	results = make([]MigrationResult, 0)
 	for _, id := range m.getSequenceIDs() {
-		if len(m.migrations[id]) < 1 {
+		file = m.migrations[id]
+		if !file.isFunc && len(file.sql) < 1 {
 			continue
 		}
-		sql = m.migrations[id]
-		properties = m.getProperties(id, batchID)
-		if msg, err = m.executeMigration(sql, id, message); err != nil {
-			return
+		result := m.newResult(file, id, batchID)
+		if m.dryRun {
+			m.planMigration(file, id, batchID)
+			results = append(results, result)
+			continue
 		}
-		messages = append(messages, msg)
-		if _, err = m.database.MakeRecord(properties, "migrations").Update("migration_id"); err != nil {
+		if err = m.executeMigration(file, m.getProperties(file, id, batchID, metadata), &result, 0); err != nil {
 			return
 		}
+		results = append(results, result)
 	}
-	message = fmt.Sprintf("%s migrations %s", m.getDirectionMessage(), strings.Join(messages, ", "))
 	return
 }
 
@@ -133,73 +250,131 @@ func (m *Migration) getSequenceIDs() []int {
 	if !m.direction {
 		sort.Sort(sort.Reverse(sort.IntSlice(sequenceIDs)))
 	}
+	if m.steps > 0 && len(sequenceIDs) > m.steps {
+		sequenceIDs = sequenceIDs[:m.steps]
+	}
 	return sequenceIDs
 }
 
-func (m *Migration) getProperties(id int, batchID int64) map[string]interface{} {
+func (m *Migration) getProperties(file *migrationFile, id int, batchID int64, metadata map[string]interface{}) map[string]interface{} {
 	properties := make(map[string]interface{})
+	for field, value := range metadata {
+		properties[field] = value
+	}
 	properties["migration_id"] = id
+	properties["dirty"] = 0
+	properties["statements_done"] = nil
 	if m.direction {
 		properties["migrated"] = "1"
 		properties["batch_id"] = strconv.FormatInt(batchID, 10)
+		if !file.isFunc {
+			properties["checksum"] = checksum(file.up, file.down)
+		}
 	} else {
 		properties["migrated"] = "0"
 	}
 	return properties
 }
 
-func (m *Migration) getDirectionMessage() string {
-	if m.direction {
-		return "Executed"
+// executeMigration runs the migration, after the first skip statements, and
+// updates its record, returning a *MigrationError if it fails
+func (m *Migration) executeMigration(file *migrationFile, properties map[string]interface{}, result *MigrationResult, skip int) (err error) {
+	if m.useTransaction(file) {
+		err = m.executeInTransaction(file, properties, result, skip)
+	} else {
+		err = m.executeStatements(file, properties, result, skip)
 	}
-	return "Reversed"
+	if err != nil {
+		return m.migrationError(result, err)
+	}
+	return nil
 }
 
-func (m *Migration) executeMigration(sql string, id int, message string) (mesage string, err error) {
-
-	// Split by the individual statements in the query
-	sqlSplit := strings.Split(sql, "[STATEMENT]")
+func (m *Migration) executeStatements(file *migrationFile, properties map[string]interface{}, result *MigrationResult, skip int) (err error) {
+	if err = m.runTimed(file, m.database, properties, result, skip, false); err != nil {
+		return
+	}
+	err = m.database.UpdateRecord(m.table(), "migration_id", properties)
+	return
+}
 
-	for _, sqlString := range sqlSplit {
-		if len(strings.Replace(sqlString, " ", "", -1)) < 1 {
+// getStatements splits the SQL of a migration into its individual statements.
+// SQL that uses the [STATEMENT] marker is split on the marker alone, as it was
+// before statements could be split on their delimiters.
+func getStatements(dialect Dialect, sql string) []string {
+	if !strings.Contains(sql, STATEMENT_MARKER) {
+		return dialect.Split(sql)
+	}
+	statements := make([]string, 0)
+	for _, sqlString := range strings.Split(sql, STATEMENT_MARKER) {
+		if len(dialect.Split(sqlString)) < 1 {
+			// Nothing but whitespace and comments
 			continue
 		}
-		_, err = m.database.Exec(sqlString, nil)
-		if err != nil {
-			return
-		}
+		statements = append(statements, sqlString)
 	}
-	message = fmt.Sprintf("%s migration #%d", message, id)
-	return message, nil
+	return statements
 }
 
 // Create makes a new migration file
 func (m *Migration) Create(migrationName string) (fullPath, fullname, message string, err error) {
-	err = m.bootstrap()
-	if err != nil {
+	if m.dryRun {
+		err = errors.New("migrations cannot be created in dry-run mode")
 		return
 	}
-	migrationName = migrationName + "." + strconv.FormatInt(time.Now().UnixNano(), 10)
-	if err = m.alreadyExists(migrationName); err != nil {
+	source, ok := m.source.(WritableSource)
+	if !ok {
+		err = fmt.Errorf("cannot create migration '%s': %w", migrationName, ErrReadOnly)
 		return
 	}
-	if fullPath, err = m.getFile(migrationName); err != nil {
+	fullname = migrationName + "." + strconv.FormatInt(time.Now().UnixNano(), 10)
+	err = m.withLock(func() (err error) {
+		if err = m.bootstrap(); err != nil {
+			return
+		}
+		if err = m.alreadyExists(fullname); err != nil {
+			return
+		}
+		if fullPath, err = source.Write(fullname, MIGRATE_DEFAULT); err != nil {
+			return
+		}
+		message, err = m.createMigrationRecord(fullname)
 		return
+	})
+	return
+}
+
+// Validate checks that the migration files are well-formed without touching the database
+func (m *Migration) Validate() error {
+	files, keys, err := m.listFiles()
+	if err != nil {
+		return err
 	}
-	if message, err = m.createMigrationRecord(migrationName); err != nil {
-		return
+	errs := duplicateKeyErrors(keys, files)
+	errs = append(errs, m.addFuncs(files, &keys))
+	if err = m.fileResult(keys, files); err != nil {
+		return err
 	}
-	return
+	for _, name := range m.files {
+		if _, ok := m.funcs[name]; ok {
+			continue
+		}
+		_, _, err = m.source.Read(name)
+		errs = append(errs, err)
+	}
+	return GetErrors(errs)
 }
 
-func (m *Migration) getFile(name string) (string, error) {
-	file := []byte(MIGRATE_DEFAULT)
-	fullPath := fmt.Sprintf("%s/%s.sql", m.path, name)
-	if err := os.WriteFile(fullPath, file, Permission); err != nil {
-		return "", err
+func duplicateKeyErrors(keys []int, files map[int]string) []error {
+	errs := make([]error, 0)
+	seen := make(map[int]bool)
+	for _, key := range keys {
+		if seen[key] {
+			errs = append(errs, fmt.Errorf("more than one migration has the timestamp %d (%s)", key, files[key]))
+		}
+		seen[key] = true
 	}
-	os.Chmod(fullPath, Permission)
-	return fullPath, nil
+	return errs
 }
 
 func (m *Migration) alreadyExists(name string) error {
@@ -215,46 +390,54 @@ func (m *Migration) alreadyExists(name string) error {
 }
 
 func (m *Migration) bootstrap() error {
-	if err := m.initTable(); err != nil {
+	if err := m.prepare(); err != nil {
 		return err
 	}
-	if err := m.initDir(); err != nil {
+	return m.getMigrationsSQL()
+}
+
+// prepare makes sure the migrations table and source exist and that every
+// migration has been recorded
+func (m *Migration) prepare() error {
+	m.seeded = make([]map[string]interface{}, 0)
+	m.plan = make(Plan, 0)
+	if err := m.initTable(); err != nil {
 		return err
 	}
-	if err := m.seed(); err != nil {
+	if err := m.initSource(); err != nil {
 		return err
 	}
-	return m.getMigrationsSQL()
+	return m.seed()
 }
 
 func (m *Migration) initTable() error {
-	hasTable, err := m.database.CheckHasTable("migrations")
+	hasTable, err := m.checkHasTable()
 	if err != nil {
 		return err
 	}
+	m.hasTable = hasTable
+	if m.dryRun {
+		return nil
+	}
 	if !hasTable {
-		return m.createTable()
+		m.hasTable = true
+		if err = m.createTable(); err != nil {
+			return err
+		}
+		return m.setTableVersion(TABLE_VERSION)
 	}
-	return nil
+	return m.upgradeTable()
 }
 
-func (m *Migration) initDir() error {
-	exists, err := m.hasPathDir()
-	if err != nil {
-		return err
-	}
-	if !exists {
-		return os.Mkdir(m.path, Permission)
+func (m *Migration) initSource() error {
+	if source, ok := m.source.(initialiser); ok && !m.dryRun {
+		return source.init()
 	}
 	return nil
 }
 
-func (m *Migration) hasPathDir() (bool, error) {
-	return DirExists(m.path)
-}
-
 func (m *Migration) createTable() error {
-	_, err := m.database.Exec(MIGS_TABLE, nil)
+	_, err := m.exec(m.dialect.CreateTable(), nil)
 	return err
 }
 
@@ -263,11 +446,12 @@ func (m *Migration) seed() error {
 		return err
 	}
 	errs := make([]error, len(m.files))
-	// result := make([]string, 0)
-	var migName string
-	for i := 0; i < len(m.files); i++ {
-		migName = m.files[i]
-		_, err := m.seedMigrationRecord(migName, i+1)
+	for i, name := range m.files {
+		// The migration id is the timestamp of the migration, which orders it
+		id, err := getKey(name)
+		if err == nil {
+			_, err = m.seedMigrationRecord(name, id)
+		}
 		errs[i] = err
 	}
 	// Pull list any errors, if any
@@ -283,7 +467,14 @@ func (m *Migration) seedMigrationRecord(name string, id int) (message string, er
 	if exists {
 		return "", nil
 	}
-	insertID, err := m.database.MakeRecord(m.zeroDayProperties(id, name), "migrations").Create()
+	if squashed, err := m.seedSquash(name); err != nil || squashed {
+		return "", err
+	}
+	if m.dryRun {
+		m.seeded = append(m.seeded, m.zeroDayProperties(id, name))
+		return "", nil
+	}
+	insertID, err := m.database.CreateRecord(m.table(), m.zeroDayProperties(id, name))
 	if err != nil {
 		return "", err
 	}
@@ -299,9 +490,14 @@ func (m *Migration) zeroDayProperties(id interface{}, name string) map[string]in
 	}
 }
 
+// createMigrationRecord records the new migration with its timestamp as its
+// migration id, as seed does
 func (m *Migration) createMigrationRecord(name string) (string, error) {
-	now := time.Time.Unix(time.Now())
-	insertID, err := m.database.MakeRecord(m.zeroDayProperties(now, name), "migrations").Create()
+	id, err := getKey(name)
+	if err != nil {
+		return "", err
+	}
+	insertID, err := m.database.CreateRecord(m.table(), m.zeroDayProperties(id, name))
 	if err != nil {
 		return "", err
 	}
@@ -309,9 +505,12 @@ func (m *Migration) createMigrationRecord(name string) (string, error) {
 }
 
 func (m *Migration) exists(name string) (bool, error) {
+	if !m.hasTable {
+		return false, nil
+	}
 	checks := make([]interface{}, 0)
 	checks = append(checks, name)
-	exists, err := m.database.QueryRaw(EXISTS_QUERY, checks)
+	exists, err := m.query(EXISTS_QUERY, checks)
 	if err != nil {
 		return false, err
 	}
@@ -321,7 +520,7 @@ func (m *Migration) exists(name string) (bool, error) {
 	return false, errors.New("error in existence checker")
 }
 
-// Lists files in migrations directory
+// Lists the migrations in the source
 func (m *Migration) getMigrationsSQL() error {
 	if err := m.findFiles(); err != nil {
 		return err
@@ -329,23 +528,28 @@ func (m *Migration) getMigrationsSQL() error {
 	if err := m.getMigCandidates(); err != nil {
 		return err
 	}
+	return m.loadCandidates()
+}
+
+// loadCandidates reads the SQL for each of the migration candidates. Those whose
+// file is missing are skipped, the missing file policy having been applied to
+// them already (see handleMissing).
+func (m *Migration) loadCandidates() error {
+	m.migrations = make(map[int]*migrationFile)
 	// Check the files found against the database
 	for _, row := range m.migrationCandidates {
 		err := m.appendContents(row)
 		if err != nil {
-			if notFound, ok := err.(*fileNotFound); ok {
-				log.Println(err.Error())
-				m.handleNotFound(notFound)
+			if _, ok := err.(*fileNotFound); ok {
 				continue
-			} else {
-				return err
 			}
+			return err
 		}
 	}
 	return nil
 }
 
-func (m *Migration) getNameAndID(v map[string]interface{}) (name string, id int64, err error) {
+func getNameAndID(v map[string]interface{}) (name string, id int64, err error) {
 	name, ok := (v["name"]).(string)
 	if !ok {
 		err = errors.New("name of migration is not a string")
@@ -368,60 +572,53 @@ func (m *Migration) getNameAndID(v map[string]interface{}) (name string, id int6
 }
 
 func (m *Migration) appendContents(row map[string]interface{}) error {
-	name, id, err := m.getNameAndID(row)
+	name, id, err := getNameAndID(row)
 	if err != nil {
 		return err
 	}
 	if err := m.nameInFile(name); err != nil {
 		return err
 	}
-	contents, err := GetFileContents(fmt.Sprintf("%s/%s.sql", m.path, name))
+	if fn, ok := m.funcs[name]; ok {
+		m.migrations[int(id)] = fn.file(name, m.direction)
+		return nil
+	}
+	up, down, err := m.source.Read(name)
 	if err != nil {
-		return errors.New("Could not get contents for migration " + name + " (id " + strconv.FormatInt(id, 10) + ")")
+		return errors.New("Could not get contents for migration " + name + " (id " + strconv.FormatInt(id, 10) + "): " + err.Error())
 	}
-	m.migrations[int(id)] = m.getMigContents(contents)
+	file := &migrationFile{name: name, up: up, down: down, sql: down}
+	if m.direction {
+		file.sql = up
+	}
+	m.migrations[int(id)] = file
 	return nil
 }
 
+// fileNotFoundErr is handled according to the missing file policy; see
+// WithMissingFiles
 func (m *Migration) fileNotFoundErr(file string) *fileNotFound {
-	// v2.0.0 - if there are missing files, rather just report a warning and
-	// delete the migration record from the database. This way the folder contents
-	// are the only source of truth
 	return &fileNotFound{
-		database: m.database,
-		file:     file,
-		message:  fmt.Sprintf("the migration file for '%s' was not found and will be ignored", file),
+		file:    file,
+		message: fmt.Sprintf("the migration file for '%s' was not found", file),
 	}
 }
 
+// findFiles lists the migrations in the source, together with the Go migrations,
+// ordered by timestamp. A source that does not exist yet has no migrations; this
+// is only possible in dry-run mode, when the source is not initialised.
 func (m *Migration) findFiles() error {
-	files := make(map[int]string)
-	keys := make([]int, 0)
-	var key int
-	err := filepath.Walk(m.path, getWalkFunc(key, &files, &keys))
+	files, keys, err := m.listFiles()
+	if errors.Is(err, fs.ErrNotExist) {
+		files, keys, err = make(map[int]string), make([]int, 0), nil
+	}
 	if err != nil {
 		return err
 	}
-	return m.fileResult(keys, files)
-}
-
-func getWalkFunc(key int, files *map[int]string, keys *[]int) filepath.WalkFunc {
-	return func(path string, fileInfo os.FileInfo, err error) error {
-		if !fileInfo.IsDir() && len(fileInfo.Name()) > 4 && strings.Index(fileInfo.Name(), ".sql") == len(fileInfo.Name())-4 {
-			fileSplit := strings.Split(fileInfo.Name(), ".")
-			if len(fileSplit) != 3 {
-				return errors.New("Migration name is malformed: Should be {name}.{timestamp}.sql")
-			}
-			fileName := fileSplit[0] + "." + fileSplit[1]
-			key, err = strconv.Atoi(fileSplit[1])
-			if err != nil {
-				return errors.New("could not parse migration file timestamp")
-			}
-			(*files)[key] = fileName
-			*keys = append(*keys, key)
-		}
-		return nil
+	if err = m.addFuncs(files, &keys); err != nil {
+		return err
 	}
+	return m.fileResult(keys, files)
 }
 
 func (m *Migration) fileResult(keys []int, files map[int]string) error {
@@ -437,13 +634,19 @@ func (m *Migration) fileResult(keys []int, files map[int]string) error {
 }
 
 func (m *Migration) getMigCandidates() error {
-	inserts, query, err := m.getQuery()
-	if err != nil {
-		return err
+	result := make([]map[string]interface{}, 0)
+	if m.hasTable {
+		inserts, query, err := m.getQuery()
+		if err != nil {
+			return err
+		}
+		if result, err = m.query(query, inserts); err != nil {
+			return err
+		}
 	}
-	result, err := m.database.QueryRaw(query, inserts)
-	if err != nil {
-		return err
+	if m.direction {
+		// Files that would have been recorded, were this not a dry run
+		result = append(result, m.seeded...)
 	}
 	m.migrationCandidates = result
 	return nil
@@ -464,23 +667,20 @@ func (m *Migration) getQuery() (inserts []interface{}, query string, err error)
 		order = ORDER_DESC
 	}
 	query = strings.Replace(MIGS_QUERY, "[order]", order, -1)
-	if batch > 0 {
-		batchStr = "and batch_id = ?"
+	if !m.direction {
+		// Migrations recorded by Baseline were never run, so are not reversed
+		batchStr = "and batch_id <> ?"
+		inserts = append(inserts, strconv.Itoa(BASELINE_BATCH))
+	}
+	// Steps are counted across batches
+	if batch > 0 && m.steps < 1 {
+		batchStr += " and batch_id = ?"
 		inserts = append(inserts, strconv.FormatInt(batch, 10))
 	}
 	query = strings.Replace(query, "[batch]", batchStr, -1)
 	return
 }
 
-func (m *Migration) getMigContents(contents string) string {
-	result := strings.Split(contents, "[DIRECTION]")
-	if m.direction {
-		return result[0]
-	}
-
-	return result[1]
-}
-
 func (m *Migration) nameInFile(name string) *fileNotFound {
 	ok := false
 	for i := 0; i < len(m.files); i++ {
@@ -494,20 +694,51 @@ func (m *Migration) nameInFile(name string) *fileNotFound {
 	return nil
 }
 
+// getLastBatch returns the latest batch that has been run, which is not the
+// batch of the latest migration when a migration is run out of order
 func (m *Migration) getLastBatch() (int64, error) {
-	if m.direction {
+	if m.direction || !m.hasTable {
 		return 0, nil
 	}
-	result, err := m.database.QueryRaw(LAST_BATCH_QUERY, nil)
+	result, err := m.query(LAST_BATCH_QUERY, nil)
 	if err != nil {
 		return 0, err
 	}
 	if len(result) < 1 {
 		return 0, nil
 	}
+	if value := result[0]["batch_id"]; value == nil || value == "" {
+		// Nothing has been run
+		return 0, nil
+	}
 	return getBatchID(result[0]["batch_id"])
 }
 
+// nextBatch numbers a new batch by the time, after every recorded batch, so that
+// runs within the same second are still separate batches
+func (m *Migration) nextBatch() (int64, error) {
+	batchID := time.Now().Unix()
+	if !m.hasTable {
+		return batchID, nil
+	}
+	result, err := m.query(MAX_BATCH_QUERY, nil)
+	if err != nil || len(result) < 1 {
+		return batchID, err
+	}
+	if value := result[0]["batch_id"]; value == nil || value == "" {
+		// The table is empty
+		return batchID, nil
+	}
+	last, err := getBatchID(result[0]["batch_id"])
+	if err != nil {
+		return 0, err
+	}
+	if last >= batchID {
+		batchID = last + 1
+	}
+	return batchID, nil
+}
+
 func getBatchID(batchID interface{}) (int64, error) {
 	if id, ok := batchID.(int64); ok {
 		return id, nil
@@ -574,9 +805,52 @@ func (f fileNotFound) Error() string {
 	return f.message
 }
 
-func (m *Migration) handleNotFound(f *fileNotFound) {
-	_, err := f.database.Exec(REMOVE_FILE, []interface{}{f.file})
-	if err != nil {
-		log.Println("warning: ", err.Error())
+// query runs a bookkeeping query, binding its placeholders for the dialect
+func (m *Migration) query(query string, inserts []interface{}) ([]map[string]interface{}, error) {
+	return m.database.QueryRaw(m.bind(query), inserts)
+}
+
+// exec runs a bookkeeping statement, binding its placeholders for the dialect
+func (m *Migration) exec(query string, inserts []interface{}) (sql.Result, error) {
+	return m.database.Exec(m.bind(query), inserts)
+}
+
+// bind names the migrations table, and the table that holds its version, in the
+// query and binds its placeholders
+func (m *Migration) bind(query string) string {
+	query = strings.ReplaceAll(query, TABLE_PLACEHOLDER, m.table())
+	query = strings.ReplaceAll(query, VERSION_TABLE_PLACEHOLDER, m.qualify(m.tableName+VERSION_TABLE_SUFFIX))
+	query = strings.ReplaceAll(query, ARCHIVE_TABLE_PLACEHOLDER, m.qualify(m.tableName+ARCHIVE_TABLE_SUFFIX))
+	return m.dialect.Bind(query)
+}
+
+// table returns the quoted name of the migrations table, qualified by its schema
+// if one has been set
+func (m *Migration) table() string {
+	return m.qualify(m.tableName)
+}
+
+func (m *Migration) qualify(name string) string {
+	if len(m.tableSchema) > 0 {
+		return m.dialect.Quote(m.tableSchema) + "." + m.dialect.Quote(name)
+	}
+	return m.dialect.Quote(name)
+}
+
+// checkHasTable reports whether the migrations table exists
+func (m *Migration) checkHasTable() (bool, error) {
+	return m.tableExists(m.tableName)
+}
+
+// tableExists reports whether the table exists in the schema of the migrations table
+func (m *Migration) tableExists(name string) (bool, error) {
+	if len(m.tableSchema) < 1 {
+		return m.database.CheckHasTable(name)
+	}
+	rows, err := m.query(m.dialect.TableQuery(), []interface{}{m.tableSchema, name})
+	if err != nil || len(rows) < 1 {
+		return false, err
 	}
+	total, _ := rows[0]["total"].(int64)
+	return total > 0, nil
 }
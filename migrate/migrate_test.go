@@ -75,7 +75,7 @@ func TestCreate(t *testing.T) {
 		t.Error(err)
 	}
 	m := Make(db, path)
-	fullPath, _, _, err := m.Create(f)
+	fullPath, fullname, _, err := m.Create(f)
 	if err != nil {
 		t.Error(err)
 	}
@@ -87,6 +87,14 @@ func TestCreate(t *testing.T) {
 	if !created {
 		t.Errorf("expected file '%s' to have been created", f)
 	}
+	// The migration id is the timestamp of the migration
+	rows, err := db.QueryRaw("SELECT migration_id FROM migrations WHERE name = ?", []interface{}{fullname})
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("expected '%s' to have been recorded (%v)", fullname, err)
+	}
+	if key, _ := getKey(fullname); fmt.Sprint(rows[0]["migration_id"]) != fmt.Sprint(key) {
+		t.Errorf("expected the migration id %d, got %v", key, rows[0]["migration_id"])
+	}
 	reset()
 }
 
@@ -96,6 +104,73 @@ func TestMigrateUpAndDown(t *testing.T) {
 	reset()
 }
 
+func TestBatchesInTheSameSecond(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	names := seedGadgets(t, path)
+	if _, err = Make(db, path).MigrateUpSteps(1); err != nil {
+		t.Fatal(err)
+	}
+	result, err := Make(db, path).MigrateUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := Make(db, path).Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Migrations) != 2 || statuses[0].BatchID >= result.Migrations[0].BatchID {
+		t.Errorf("expected the second run to be a later batch than %d, got %+v", statuses[0].BatchID, result.Migrations)
+	}
+	// Only the second run is reversed
+	if _, err = Make(db, path).MigrateDown(); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, path, map[string]bool{names[0]: true, names[1]: false, names[2]: false})
+}
+
+func TestMigrateDownOutOfOrder(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	names := make([]string, 0)
+	for _, mig := range []struct {
+		name      string
+		timestamp int64
+		content   string
+	}{
+		{"create_gadgets_table", 1, TEST_GADGETS_TABLE},
+		{"alter_gadgets_add_weight", 3, TEST_ALTER_GADGETS_AGAIN},
+	} {
+		name, err := writeMigration(path, mig.name, mig.timestamp, mig.content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if _, err = Make(db, path).MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	// A migration older than those run is run in a later batch
+	pending, err := writeMigration(path, "alter_gadgets_add_colour", 2, TEST_ALTER_GADGETS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Make(db, path).MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	// Only that later batch is reversed
+	if _, err = Make(db, path).MigrateDown(); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, path, map[string]bool{names[0]: true, pending: false, names[1]: true})
+}
+
 func checkMigrateUp(t *testing.T) {
 	path, err := getTestDir()
 	if err != nil {
@@ -135,7 +210,7 @@ func runSecondMigration(t *testing.T, path, faultyMigName string) {
 	}
 
 	// This will try run the migration that has been created as well as the faulty one
-	_, err = Make(db, path).MigrateUp()
+	_, err = Make(db, path, WithMissingFiles(MISSING_FILES_DELETE)).MigrateUp()
 	if err != nil {
 		t.Error(err)
 		return
@@ -236,7 +311,7 @@ func clearTestDir() error {
 }
 
 func reset() {
-	_, err := db.Exec("DROP TABLE IF EXISTS migrations;", nil)
+	_, err := db.Exec("DROP TABLE IF EXISTS migrations, migrations_version, migrations_archive;", nil)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,117 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+// MissingFilePolicy decides what happens to the record of a migration whose file
+// is no longer in the source, as when a process running an older build sees the
+// migrations of a newer one
+type MissingFilePolicy string
+
+const (
+	MISSING_FILES_WARN    MissingFilePolicy = "warn"    // log a warning and ignore the migration, keeping its record
+	MISSING_FILES_ERROR   MissingFilePolicy = "error"   // fail with ErrMissingFile
+	MISSING_FILES_ARCHIVE MissingFilePolicy = "archive" // move the record to the archive table
+	MISSING_FILES_DELETE  MissingFilePolicy = "delete"  // delete the record
+
+	ARCHIVE_TABLE_SUFFIX      = "_archive"
+	ARCHIVE_TABLE_PLACEHOLDER = "[archive_table]"
+	ARCHIVE_TABLE             = `CREATE TABLE [archive_table] (
+	migration_id BIGINT,
+	batch_id BIGINT,
+	name VARCHAR(1000) NOT NULL,
+	migrated SMALLINT,
+	checksum VARCHAR(64) NULL,
+	archived_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)`
+	ARCHIVE_INSERT = `INSERT INTO [archive_table] (migration_id, batch_id, name, migrated, checksum)
+	SELECT migration_id, batch_id, name, migrated, checksum FROM [table] WHERE name = ?`
+)
+
+// ErrMissingFile is returned under MISSING_FILES_ERROR when the file of a
+// recorded migration is not in the source
+var ErrMissingFile = errors.New("the file of a recorded migration is missing")
+
+// MissingFilePolicies lists the policies that WithMissingFiles accepts
+var MissingFilePolicies = []MissingFilePolicy{MISSING_FILES_WARN, MISSING_FILES_ERROR, MISSING_FILES_ARCHIVE, MISSING_FILES_DELETE}
+
+// handleMissing applies the missing file policy to every recorded migration
+// whose file is not in the source, before anything is planned
+func (m *Migration) handleMissing() error {
+	if !m.hasTable {
+		return nil
+	}
+	rows, err := m.query(STATUS_QUERY, nil)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		name, _, err := getNameAndID(row)
+		if err != nil {
+			return err
+		}
+		if notFound := m.nameInFile(name); notFound != nil {
+			if err = m.handleNotFound(notFound); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// handleNotFound deals with the record of a migration whose file is missing
+// according to the policy. Records are left alone in dry-run mode.
+func (m *Migration) handleNotFound(f *fileNotFound) error {
+	policy := m.missingFiles
+	if m.dryRun && (policy == MISSING_FILES_ARCHIVE || policy == MISSING_FILES_DELETE) {
+		policy = MISSING_FILES_WARN
+	}
+	switch policy {
+	case MISSING_FILES_WARN:
+		log.Printf("warning: %s and will be ignored\n", f.Error())
+		return nil
+	case MISSING_FILES_ERROR:
+		return fmt.Errorf("%w: %s", ErrMissingFile, f.Error())
+	case MISSING_FILES_ARCHIVE:
+		if err := m.archiveRecord(f.file); err != nil {
+			return err
+		}
+		log.Printf("warning: %s; its record has been moved to %s\n", f.Error(), m.qualify(m.tableName+ARCHIVE_TABLE_SUFFIX))
+		return nil
+	case MISSING_FILES_DELETE:
+		if _, err := m.exec(REMOVE_FILE, []interface{}{f.file}); err != nil {
+			return err
+		}
+		log.Printf("warning: %s; its record has been deleted\n", f.Error())
+		return nil
+	}
+	return fmt.Errorf("unknown missing file policy '%s'", m.missingFiles)
+}
+
+// archiveRecord moves the record of the migration to the archive table,
+// creating the table if need be
+func (m *Migration) archiveRecord(name string) error {
+	hasTable, err := m.tableExists(m.tableName + ARCHIVE_TABLE_SUFFIX)
+	if err != nil {
+		return err
+	}
+	if !hasTable {
+		if _, err = m.exec(ARCHIVE_TABLE, nil); err != nil {
+			return err
+		}
+	}
+	if _, err = m.exec(ARCHIVE_INSERT, []interface{}{name}); err != nil {
+		return err
+	}
+	_, err = m.exec(REMOVE_FILE, []interface{}{name})
+	return err
+}
+
+// isBookkeeping reports whether the table is one that the package keeps
+// alongside the migrations table
+func (m *Migration) isBookkeeping(name string) bool {
+	return name == m.tableName || name == m.tableName+VERSION_TABLE_SUFFIX || name == m.tableName+ARCHIVE_TABLE_SUFFIX
+}
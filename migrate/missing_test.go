@@ -0,0 +1,96 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestMissingFiles(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	names := seedGadgets(t, path)
+	if _, err = Make(db, path).MigrateTo(names[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Remove(fmt.Sprintf("%s/%s.sql", path, names[2])); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = Make(db, path, WithMissingFiles(MISSING_FILES_ERROR)).MigrateUp(); !errors.Is(err, ErrMissingFile) {
+		t.Errorf("expected ErrMissingFile, got %v", err)
+	}
+	checkMigrated(t, path, map[string]bool{names[1]: false})
+	if _, err = Make(db, path, WithMissingFiles("keep")).MigrateUp(); err == nil {
+		t.Errorf("expected an error for an unknown policy")
+	}
+
+	// By default the migration is ignored and its record kept
+	if _, err = Make(db, path).MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, path, map[string]bool{names[1]: true})
+	checkRecords(t, "migrations", names[2], 1)
+
+	if _, err = Make(db, path, WithMissingFiles(MISSING_FILES_ARCHIVE)).MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	checkRecords(t, "migrations", names[2], 0)
+	checkRecords(t, "migrations_archive", names[2], 1)
+
+	// The record of a migration that has been run is kept when reversing
+	if err = os.Remove(fmt.Sprintf("%s/%s.sql", path, names[1])); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Make(db, path).MigrateDownSteps(1); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, path, map[string]bool{names[1]: true})
+
+	// The policy applies to migrations that have been run, though there is
+	// nothing to run
+	if _, err = Make(db, path, WithMissingFiles(MISSING_FILES_ERROR)).MigrateUp(); !errors.Is(err, ErrMissingFile) {
+		t.Errorf("expected ErrMissingFile for a migration that has been run, got %v", err)
+	}
+	if _, err = Make(db, path, WithMissingFiles(MISSING_FILES_ARCHIVE)).MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	checkRecords(t, "migrations", names[1], 0)
+	checkRecords(t, "migrations_archive", names[1], 1)
+}
+
+func TestMissingFilesMigrateTo(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	names := seedGadgets(t, path)
+	if _, err = Make(db, path).MigrateTo(names[1]); err != nil {
+		t.Fatal(err)
+	}
+	// The file of a later migration, which would be neither run nor reversed,
+	// is missing; the policy fails before anything is reversed
+	if err = os.Remove(fmt.Sprintf("%s/%s.sql", path, names[2])); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Make(db, path, WithMissingFiles(MISSING_FILES_ERROR)).MigrateTo(names[0]); !errors.Is(err, ErrMissingFile) {
+		t.Errorf("expected ErrMissingFile before anything is reversed, got %v", err)
+	}
+	checkMigrated(t, path, map[string]bool{names[0]: true, names[1]: true})
+}
+
+// checkRecords checks how many times the migration is recorded in the table
+func checkRecords(t *testing.T, table, name string, expected int) {
+	rows, err := db.QueryRaw(fmt.Sprintf("SELECT COUNT(*) AS total FROM %s WHERE name = ?", table), []interface{}{name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total, _ := getInt(rows[0]["total"]); total != expected {
+		t.Errorf("expected '%s' to be recorded %d times in %s, got %d", name, expected, table, total)
+	}
+}
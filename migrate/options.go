@@ -0,0 +1,95 @@
+package migrate
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Option configures a Migration; pass options to Make
+type Option func(*Migration)
+
+// WithDryRun plans migrations without running them. Nothing is written to the
+// database, the migrations table or the migrations directory; the statements
+// that would have been executed are available through Plan.
+func WithDryRun() Option {
+	return func(m *Migration) {
+		m.dryRun = true
+	}
+}
+
+// WithConnection supplies the connection pool behind the database, which allows
+// each migration to be run in a transaction together with the update of its
+// record in the migrations table. Files containing the [NO TRANSACTION]
+// directive are run without one. It is not needed for executors adapted with
+// FromDB or FromConn.
+func WithConnection(conn *sql.DB) Option {
+	return func(m *Migration) {
+		m.conn = &sqlExecutor{runner: conn, db: conn}
+	}
+}
+
+// WithLockTimeout sets how long to wait for another process to finish running
+// migrations before giving up with ErrLocked. The default is DEFAULT_LOCK_TIMEOUT.
+// Locking requires a connection; see WithConnection.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(m *Migration) {
+		m.lockTimeout = timeout
+	}
+}
+
+// WithLockFailFast returns ErrLocked straight away, rather than waiting, when
+// another process is running migrations
+func WithLockFailFast() Option {
+	return WithLockTimeout(0)
+}
+
+// WithDialect sets the dialect of the database engine; the default is MySQL.
+// Other engines need an executor adapted with FromDB, FromConn or FromTx.
+func WithDialect(dialect Dialect) Option {
+	return func(m *Migration) {
+		m.dialect = dialect
+	}
+}
+
+// WithTable sets the name of the table that records the migrations, which is
+// migrations by default, for databases in which that name is taken
+func WithTable(name string) Option {
+	return func(m *Migration) {
+		m.tableName = name
+	}
+}
+
+// WithTableSchema keeps the table that records the migrations in the schema,
+// rather than the current schema. The schema should already exist.
+func WithTableSchema(schema string) Option {
+	return func(m *Migration) {
+		m.tableSchema = schema
+	}
+}
+
+// WithAppVersion records the version of the application, such as a release tag
+// or commit, with each migration that is run or reversed (see MigrationStatus)
+func WithAppVersion(version string) Option {
+	return func(m *Migration) {
+		m.appVersion = version
+	}
+}
+
+// WithStrictChecksums refuses to run migrations while any migration that has
+// already been run has changed since (see Verify), returning ErrDrift
+func WithStrictChecksums() Option {
+	return func(m *Migration) {
+		m.strict = true
+	}
+}
+
+// WithMissingFiles sets what happens to the record of a migration whose file is
+// no longer in the source. The policy is applied to every such record, whether
+// or not the migration has been run, each time migrations are run or reversed,
+// before anything is planned. The default, MISSING_FILES_WARN, never erases the
+// record of a migration that may have been run.
+func WithMissingFiles(policy MissingFilePolicy) Option {
+	return func(m *Migration) {
+		m.missingFiles = policy
+	}
+}
@@ -0,0 +1,81 @@
+package migrate
+
+import (
+	"fmt"
+	"strings"
+)
+
+type (
+	// PlannedMigration is a migration that would have been run or reversed in
+	// dry-run mode
+	PlannedMigration struct {
+		Name        string
+		MigrationID int64
+		Up          bool
+		BatchID     int64 // the batch the migration would have been run in; zero when reversing
+		Statements  []string
+		Func        bool // the migration is a Go function, whose statements are not known until it runs
+	}
+
+	// Plan lists the planned migrations in the order in which they would have been run
+	Plan []PlannedMigration
+)
+
+// Plan returns the migrations that the last call to MigrateUp, MigrateDown,
+// MigrateTo or their step variants would have run in dry-run mode
+func (m *Migration) Plan() Plan {
+	return m.plan
+}
+
+func (m *Migration) planMigration(file *migrationFile, id int, batchID int64) {
+	planned := PlannedMigration{
+		Name:        file.name,
+		MigrationID: int64(id),
+		Up:          m.direction,
+		Statements:  make([]string, 0),
+		Func:        file.isFunc,
+	}
+	if m.direction {
+		planned.BatchID = batchID
+	}
+	for _, statement := range getStatements(m.dialect, file.sql) {
+		planned.Statements = append(planned.Statements, strings.TrimSpace(statement))
+	}
+	m.plan = append(m.plan, planned)
+}
+
+// Direction returns "up" if the migration would have been run and "down" if it
+// would have been reversed
+func (p PlannedMigration) Direction() string {
+	if p.Up {
+		return DIRECTION_UP
+	}
+	return DIRECTION_DOWN
+}
+
+// String renders the plan as SQL, with a comment introducing each migration
+func (p Plan) String() string {
+	if len(p) < 1 {
+		return "-- nothing to migrate\n"
+	}
+	var b strings.Builder
+	for _, planned := range p {
+		fmt.Fprintf(&b, "-- %s %s (migration #%d", planned.Direction(), planned.Name, planned.MigrationID)
+		if planned.Up {
+			fmt.Fprintf(&b, ", batch %d", planned.BatchID)
+		}
+		b.WriteString(")\n")
+		if planned.Func {
+			b.WriteString("-- runs a Go function\n")
+		}
+		for _, statement := range planned.Statements {
+			b.WriteString(statement)
+			if !strings.HasSuffix(statement, ";") {
+				b.WriteString(";")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,57 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	names := seedGadgets(t, path)
+	m := Make(db, path, WithDryRun())
+	if _, err = m.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	plan := m.Plan()
+	if len(plan) != 3 {
+		t.Fatalf("expected 3 planned migrations, got %d", len(plan))
+	}
+	if plan[0].Name != names[0] || !plan[0].Up || plan[0].BatchID < 1 {
+		t.Errorf("expected '%s' to be planned first, as a new batch, got %+v", names[0], plan[0])
+	}
+	if !strings.Contains(plan.String(), "CREATE TABLE gadgets") {
+		t.Errorf("expected the plan to include the statements, got:\n%s", plan.String())
+	}
+	checkNothingWritten(t)
+
+	if _, err = Make(db, path).MigrateUpSteps(2); err != nil {
+		t.Fatal(err)
+	}
+	m = Make(db, path, WithDryRun())
+	if _, err = m.MigrateTo(names[0]); err != nil {
+		t.Fatal(err)
+	}
+	if plan = m.Plan(); len(plan) != 1 || plan[0].Name != names[1] || plan[0].Up {
+		t.Errorf("expected only '%s' to be planned for reversal, got %+v", names[1], plan)
+	}
+	checkMigrated(t, path, map[string]bool{names[0]: true, names[1]: true, names[2]: false})
+	if _, _, _, err = m.Create("not_in_dry_run"); err == nil {
+		t.Errorf("expected Create to fail in dry-run mode")
+	}
+}
+
+func checkNothingWritten(t *testing.T) {
+	for _, table := range []string{"migrations", "gadgets"} {
+		hasTable, err := db.CheckHasTable(table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hasTable {
+			t.Errorf("expected table '%s' not to have been created in dry-run mode", table)
+		}
+	}
+}
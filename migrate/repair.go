@@ -0,0 +1,219 @@
+package migrate
+
+import (
+	"fmt"
+)
+
+const (
+	INCONSISTENCY_ORPHANED    = "orphaned"
+	INCONSISTENCY_DUPLICATE   = "duplicate"
+	INCONSISTENCY_ID_MISMATCH = "id mismatch"
+	INCONSISTENCY_DIRTY       = "dirty"
+
+	REPAIR_QUERY  = "SELECT * FROM [table] ORDER BY migration_id ASC, id ASC"
+	REPAIR_DELETE = "DELETE FROM [table] WHERE id = ?"
+	REPAIR_ID     = "UPDATE [table] SET migration_id = ? WHERE id = ?"
+)
+
+// Inconsistency is a disagreement between the migrations table and the source
+// found by Repair
+type Inconsistency struct {
+	Kind        string // one of the INCONSISTENCY_* constants
+	Name        string
+	MigrationID int64
+	Description string // what is wrong
+	Fix         string // what Repair does about it
+	Fixable     bool   // false for dirty migrations, which are dealt with by Resume or Force, and see orphan
+	Fixed       bool
+	fix         func() error
+}
+
+func (i Inconsistency) String() string {
+	return fmt.Sprintf("%s (%s): %s; %s", i.Name, i.Kind, i.Description, i.Fix)
+}
+
+// Repair reports every inconsistency between the migrations table and the
+// source: records of migrations that are not in the source, migrations recorded
+// more than once, migration ids that do not match the timestamps of the
+// migrations, which order them, and dirty migrations. Each inconsistency that
+// can be fixed is fixed if confirm returns true for it; pass nil to only report
+// them. Records of migrations that have been run are only archived or deleted
+// under that missing file policy (see WithMissingFiles). Nothing is fixed in
+// dry-run mode.
+func (m *Migration) Repair(confirm func(Inconsistency) bool) (found []Inconsistency, err error) {
+	err = m.withLock(func() error {
+		if err := m.initTable(); err != nil {
+			return err
+		}
+		if err := m.findFiles(); err != nil {
+			return err
+		}
+		if found, err = m.inconsistencies(); err != nil {
+			return err
+		}
+		if confirm == nil || m.dryRun {
+			return nil
+		}
+		for i := range found {
+			if !found[i].Fixable || !confirm(found[i]) {
+				continue
+			}
+			if err := found[i].fix(); err != nil {
+				return fmt.Errorf("could not repair %s: %w", found[i].Name, err)
+			}
+			found[i].Fixed = true
+		}
+		return nil
+	})
+	return
+}
+
+func (m *Migration) inconsistencies() ([]Inconsistency, error) {
+	found := make([]Inconsistency, 0)
+	if !m.hasTable {
+		return found, nil
+	}
+	rows, err := m.query(REPAIR_QUERY, nil)
+	if err != nil {
+		return nil, err
+	}
+	inSource := make(map[string]bool)
+	for _, name := range m.files {
+		inSource[name] = true
+	}
+	names := make([]string, 0)
+	byName := make(map[string][]map[string]interface{})
+	for _, row := range rows {
+		name, id, err := getNameAndID(row)
+		if err != nil {
+			return nil, err
+		}
+		if !inSource[name] {
+			found = append(found, m.orphan(row))
+			continue
+		}
+		if _, ok := byName[name]; !ok {
+			names = append(names, name)
+		}
+		byName[name] = append(byName[name], row)
+		if dirty, _ := getMigrated(row["dirty"]); dirty {
+			done, _ := getInt(row["statements_done"])
+			found = append(found, Inconsistency{
+				Kind:        INCONSISTENCY_DIRTY,
+				Name:        name,
+				MigrationID: id,
+				Description: fmt.Sprintf("failed part of the way through (%s) after %d statements", getString(row["direction"]), done),
+				Fix:         "finish it with Resume or set its state with Force",
+			})
+		}
+	}
+	for _, name := range names {
+		records := byName[name]
+		kept := keptRecord(records)
+		for i, row := range records {
+			if i != kept {
+				found = append(found, m.deletion(INCONSISTENCY_DUPLICATE, row, fmt.Sprintf("recorded %d times", len(records))))
+			}
+		}
+		if mismatch, ok := m.idMismatch(records[kept]); ok {
+			found = append(found, mismatch)
+		}
+	}
+	return found, nil
+}
+
+// keptRecord chooses which of the records of a migration to keep: the first that
+// has been run, or else the first whose id matches its timestamp
+func keptRecord(records []map[string]interface{}) int {
+	matching := -1
+	for i, row := range records {
+		if migrated, _ := getMigrated(row["migrated"]); migrated {
+			return i
+		}
+		name, id, _ := getNameAndID(row)
+		if key, err := getKey(name); err == nil && int64(key) == id && matching < 0 {
+			matching = i
+		}
+	}
+	if matching < 0 {
+		return 0
+	}
+	return matching
+}
+
+// orphan is the inconsistency of a record whose migration is not in the source.
+// The record of a migration that has been run is history, so it is only fixed as
+// the missing file policy says: archived, deleted, or else left alone.
+func (m *Migration) orphan(row map[string]interface{}) Inconsistency {
+	description := "recorded, but not in the source"
+	if migrated, _ := getMigrated(row["migrated"]); !migrated {
+		return m.deletion(INCONSISTENCY_ORPHANED, row, description)
+	}
+	description = "recorded as run, but not in the source"
+	switch m.missingFiles {
+	case MISSING_FILES_DELETE:
+		return m.deletion(INCONSISTENCY_ORPHANED, row, description)
+	case MISSING_FILES_ARCHIVE:
+		name, id, _ := getNameAndID(row)
+		return Inconsistency{
+			Kind:        INCONSISTENCY_ORPHANED,
+			Name:        name,
+			MigrationID: id,
+			Description: description,
+			Fix:         "move the record to " + m.qualify(m.tableName+ARCHIVE_TABLE_SUFFIX),
+			Fixable:     true,
+			fix: func() error {
+				return m.archiveRecord(name)
+			},
+		}
+	}
+	name, id, _ := getNameAndID(row)
+	return Inconsistency{
+		Kind:        INCONSISTENCY_ORPHANED,
+		Name:        name,
+		MigrationID: id,
+		Description: description,
+		Fix:         "restore its file, or archive or delete its record with WithMissingFiles",
+	}
+}
+
+// deletion is an inconsistency fixed by deleting the record
+func (m *Migration) deletion(kind string, row map[string]interface{}, description string) Inconsistency {
+	name, id, _ := getNameAndID(row)
+	recordID, _ := getInt(row["id"])
+	return Inconsistency{
+		Kind:        kind,
+		Name:        name,
+		MigrationID: id,
+		Description: description,
+		Fix:         fmt.Sprintf("delete the record with id %d", recordID),
+		Fixable:     true,
+		fix: func() error {
+			_, err := m.exec(REPAIR_DELETE, []interface{}{recordID})
+			return err
+		},
+	}
+}
+
+// idMismatch returns the inconsistency if the migration id of the record is not
+// the timestamp of the migration
+func (m *Migration) idMismatch(row map[string]interface{}) (Inconsistency, bool) {
+	name, id, _ := getNameAndID(row)
+	key, err := getKey(name)
+	if err != nil || int64(key) == id {
+		return Inconsistency{}, false
+	}
+	recordID, _ := getInt(row["id"])
+	return Inconsistency{
+		Kind:        INCONSISTENCY_ID_MISMATCH,
+		Name:        name,
+		MigrationID: id,
+		Description: fmt.Sprintf("the migration id %d is not the timestamp %d", id, key),
+		Fix:         fmt.Sprintf("set the migration id to %d", key),
+		Fixable:     true,
+		fix: func() error {
+			_, err := m.exec(REPAIR_ID, []interface{}{key, recordID})
+			return err
+		},
+	}, true
+}
@@ -0,0 +1,129 @@
+package migrate
+
+import (
+	"testing"
+)
+
+func TestRepair(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	names := seedGadgets(t, path)
+	if _, err = Make(db, path).MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	for _, query := range []string{
+		"INSERT INTO migrations (migration_id, batch_id, name, migrated) VALUES (4, 0, 'dropped_migration.4', 0)",
+		"INSERT INTO migrations (migration_id, batch_id, name, migrated) VALUES (1, 0, '" + names[0] + "', 0)",
+		"UPDATE migrations SET migration_id = 7 WHERE name = '" + names[1] + "'",
+		"UPDATE migrations SET dirty = 1, statements_done = 1, direction = 'up' WHERE name = '" + names[2] + "'",
+	} {
+		if _, err = db.Exec(query, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	found, err := Make(db, path).Repair(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkInconsistencies(t, found, map[string]string{
+		INCONSISTENCY_ORPHANED:    "dropped_migration.4",
+		INCONSISTENCY_DUPLICATE:   names[0],
+		INCONSISTENCY_ID_MISMATCH: names[1],
+		INCONSISTENCY_DIRTY:       names[2],
+	}, 0)
+
+	// Nothing is fixed in dry-run mode
+	all := func(Inconsistency) bool { return true }
+	if found, err = Make(db, path, WithDryRun()).Repair(all); err != nil {
+		t.Fatal(err)
+	}
+	checkInconsistencies(t, found, nil, 0)
+
+	// Only what is confirmed is fixed
+	found, err = Make(db, path).Repair(func(i Inconsistency) bool {
+		return i.Kind != INCONSISTENCY_DUPLICATE
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkInconsistencies(t, found, nil, 2)
+	if found, err = Make(db, path).Repair(all); err != nil {
+		t.Fatal(err)
+	}
+	checkInconsistencies(t, found, map[string]string{
+		INCONSISTENCY_DUPLICATE: names[0],
+		INCONSISTENCY_DIRTY:     names[2],
+	}, 1)
+
+	// The record of the migration that has been run is the one kept
+	checkMigrated(t, path, map[string]bool{names[0]: true, names[1]: true})
+	if found, err = Make(db, path).Repair(nil); err != nil {
+		t.Fatal(err)
+	}
+	checkInconsistencies(t, found, map[string]string{INCONSISTENCY_DIRTY: names[2]}, 0)
+	rows, err := db.QueryRaw("SELECT migration_id FROM migrations WHERE name = ?", []interface{}{names[1]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := getInt(rows[0]["migration_id"]); id != 2 {
+		t.Errorf("expected the migration id of '%s' to have been set to its timestamp, got %d", names[1], id)
+	}
+}
+
+func TestRepairAppliedOrphan(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	seedGadgets(t, path)
+	if _, err = Make(db, path).MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.Exec("INSERT INTO migrations (migration_id, batch_id, name, migrated) VALUES (5, 2, 'applied_migration.5', 1)", nil); err != nil {
+		t.Fatal(err)
+	}
+	all := func(Inconsistency) bool { return true }
+
+	// The record of a migration that has been run is kept by default
+	found, err := Make(db, path).Repair(all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkInconsistencies(t, found, map[string]string{INCONSISTENCY_ORPHANED: "applied_migration.5"}, 0)
+	if found[0].Fixable {
+		t.Errorf("expected the record of a migration that has been run not to be fixable by default")
+	}
+	checkRecords(t, "migrations", "applied_migration.5", 1)
+
+	if found, err = Make(db, path, WithMissingFiles(MISSING_FILES_ARCHIVE)).Repair(all); err != nil {
+		t.Fatal(err)
+	}
+	checkInconsistencies(t, found, map[string]string{INCONSISTENCY_ORPHANED: "applied_migration.5"}, 1)
+	checkRecords(t, "migrations", "applied_migration.5", 0)
+	checkRecords(t, "migrations_archive", "applied_migration.5", 1)
+}
+
+// checkInconsistencies checks the migration found for each kind of
+// inconsistency, if expected, and how many were fixed
+func checkInconsistencies(t *testing.T, found []Inconsistency, expected map[string]string, fixed int) {
+	if expected != nil && len(found) != len(expected) {
+		t.Errorf("expected %d inconsistencies, got %v", len(expected), found)
+	}
+	total := 0
+	for _, inconsistency := range found {
+		if name, ok := expected[inconsistency.Kind]; expected != nil && (!ok || name != inconsistency.Name) {
+			t.Errorf("did not expect %s", inconsistency)
+		}
+		if inconsistency.Fixed {
+			total++
+		}
+	}
+	if total != fixed {
+		t.Errorf("expected %d inconsistencies to have been fixed, got %d", fixed, total)
+	}
+}
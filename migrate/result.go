@@ -0,0 +1,86 @@
+package migrate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type (
+	// MigrationResult describes a migration that was run or reversed
+	MigrationResult struct {
+		Name         string
+		MigrationID  int64
+		Direction    string        // DIRECTION_UP or DIRECTION_DOWN
+		BatchID      int64         // the batch the migration was run in; zero when reversing
+		Statements   int           // the number of statements; zero for Go migrations
+		Duration     time.Duration // how long the statements took; zero in dry-run mode
+		RowsAffected int64         // as reported by the driver; zero for Go migrations
+		Func         bool          // the migration is a Go function
+	}
+
+	// Result lists the migrations run or reversed by MigrateUp, MigrateDown,
+	// MigrateTo or their step variants, in the order in which they were run
+	Result struct {
+		DryRun     bool // nothing was run; the statements are available through Plan
+		Migrations []MigrationResult
+	}
+)
+
+// String describes the result for people, one migration per line
+func (r Result) String() string {
+	if len(r.Migrations) < 1 {
+		return "No migrations to run"
+	}
+	lines := make([]string, 0, len(r.Migrations))
+	for _, migration := range r.Migrations {
+		lines = append(lines, migration.describe(r.DryRun))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// newResult describes the migration before it is run
+func (m *Migration) newResult(file *migrationFile, id int, batchID int64) MigrationResult {
+	result := MigrationResult{Name: file.name, MigrationID: int64(id), Direction: DIRECTION_DOWN, Func: file.isFunc}
+	if m.direction {
+		result.Direction = DIRECTION_UP
+		result.BatchID = batchID
+	}
+	if !file.isFunc {
+		result.Statements = len(getStatements(m.dialect, file.sql))
+	}
+	return result
+}
+
+func (r MigrationResult) describe(dryRun bool) string {
+	verb := "Ran"
+	switch {
+	case dryRun && r.Direction == DIRECTION_DOWN:
+		verb = "Would have reversed"
+	case dryRun:
+		verb = "Would have run"
+	case r.Direction == DIRECTION_DOWN:
+		verb = "Reversed"
+	}
+	batch := ""
+	if r.BatchID > 0 {
+		batch = fmt.Sprintf(" in batch %d", r.BatchID)
+	}
+	details := make([]string, 0, 3)
+	if r.Func {
+		details = append(details, "Go function")
+	} else {
+		details = append(details, plural(r.Statements, "statement"))
+	}
+	if !dryRun {
+		details = append(details, plural(int(r.RowsAffected), "row")+" affected", r.Duration.Round(time.Microsecond).String())
+	}
+	return fmt.Sprintf("%s %s%s (%s)", verb, r.Name, batch, strings.Join(details, ", "))
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,70 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResult(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	names := seedGadgets(t, path)
+	inserted, err := writeMigration(path, "insert_gadgets", 4, "INSERT INTO gadgets (name) VALUES ('sprocket'), ('widget');\n-- [DIRECTION]\nDELETE FROM gadgets;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names = append(names, inserted)
+	result, err := Make(db, path).MigrateUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Migrations) != len(names) {
+		t.Fatalf("expected %d migrations to have been run, got %d", len(names), len(result.Migrations))
+	}
+	for i, migration := range result.Migrations {
+		if migration.Name != names[i] || migration.MigrationID != int64(i+1) || migration.Direction != DIRECTION_UP || migration.BatchID < 1 || migration.Statements != 1 {
+			t.Errorf("expected '%s' to have been run with one statement, got %+v", names[i], migration)
+		}
+	}
+	if rows := result.Migrations[3].RowsAffected; rows != 2 {
+		t.Errorf("expected the insert to have affected 2 rows, got %d", rows)
+	}
+
+	result, err = Make(db, path).MigrateDownSteps(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Migrations) != 1 || result.Migrations[0].Direction != DIRECTION_DOWN || result.Migrations[0].BatchID != 0 || result.Migrations[0].RowsAffected != 2 {
+		t.Errorf("expected the insert to have been reversed, got %+v", result.Migrations)
+	}
+
+	result, err = Make(db, path, WithDryRun()).MigrateTo(names[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.DryRun || len(result.Migrations) != 2 || result.Migrations[0].Name != names[2] {
+		t.Errorf("expected the dry run to list the migrations it would have reversed, got %+v", result)
+	}
+}
+
+func TestResultString(t *testing.T) {
+	result := Result{Migrations: []MigrationResult{
+		{Name: "create_gadgets_table.1", Direction: DIRECTION_UP, BatchID: 1700000000, Statements: 2, Duration: 1500 * time.Microsecond, RowsAffected: 1},
+		{Name: "seed_gadgets.2", Direction: DIRECTION_DOWN, Func: true},
+	}}
+	expected := "Ran create_gadgets_table.1 in batch 1700000000 (2 statements, 1 row affected, 1.5ms)\nReversed seed_gadgets.2 (Go function, 0 rows affected, 0s)"
+	if result.String() != expected {
+		t.Errorf("expected '%s', got '%s'", expected, result.String())
+	}
+	result.DryRun = true
+	if !strings.HasPrefix(result.String(), "Would have run create_gadgets_table.1 in batch 1700000000 (2 statements)\nWould have reversed") {
+		t.Errorf("expected the dry run to describe what would have happened, got '%s'", result.String())
+	}
+	if (Result{}).String() != "No migrations to run" {
+		t.Errorf("expected an empty result to say so, got '%s'", (Result{}).String())
+	}
+}
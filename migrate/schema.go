@@ -0,0 +1,363 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	SCHEMA_TABLES_QUERY     = "SELECT table_name AS name, table_type AS type FROM information_schema.tables WHERE table_schema = DATABASE() ORDER BY table_name ASC"
+	SCHEMA_REFERENCES_QUERY = "SELECT table_name AS name, referenced_table_name AS referenced FROM information_schema.referential_constraints WHERE constraint_schema = DATABASE()"
+	SCHEMA_ROUTINES_QUERY   = "SELECT routine_name AS name, routine_type AS type FROM information_schema.routines WHERE routine_schema = DATABASE() ORDER BY routine_type DESC, routine_name ASC"
+	SCHEMA_TRIGGERS_QUERY   = "SHOW TRIGGERS"
+	SCHEMA_APPLIED_QUERY    = "SELECT name, checksum FROM [table] WHERE migrated = 1 ORDER BY migration_id ASC"
+	SCHEMA_MIGRATED_QUERY   = "SELECT count(*) AS total FROM [table] WHERE migrated = 1"
+	SCHEMA_MARK_QUERY       = "UPDATE [table] SET migrated = 1, batch_id = ?, checksum = ? WHERE name = ?"
+	SCHEMA_HEADER           = "-- Schema dumped by MySqlMigrate. Do not edit; load it into a new database with LoadSchema.\n"
+	MIGRATED_MARKER         = "-- [MIGRATED]"
+	SCHEMA_DELIMITER        = ";;"
+)
+
+var (
+	autoIncrement = regexp.MustCompile(` AUTO_INCREMENT=\d+`)
+	definer       = regexp.MustCompile("DEFINER=`[^`]*`@`[^`]*` ")
+	identifier    = regexp.MustCompile("`((?:[^`]|``)*)`|[\\w$]+")
+)
+
+// DumpSchema returns a deterministic dump of the schema: the tables, other than
+// the migrations table, in the order their foreign keys need, then the views, in
+// the order their definitions need, then the triggers and routines, each in
+// order of name. AUTO_INCREMENT counters and definers are left out. The
+// migrations that have been run are listed at the top, so that LoadSchema can
+// record them. It is only supported for MySQL.
+func (m *Migration) DumpSchema() (string, error) {
+	if err := m.checkSchemaDialect(); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString(SCHEMA_HEADER)
+	if err := m.dumpMigrated(&b); err != nil {
+		return "", err
+	}
+	tables, views, err := m.schemaTables()
+	if err != nil {
+		return "", err
+	}
+	for _, table := range tables {
+		if err = m.dumpCreate(&b, "TABLE", table, "Create Table", false); err != nil {
+			return "", err
+		}
+	}
+	creates := make(map[string]string)
+	for _, view := range views {
+		if creates[view], err = m.showCreate("VIEW", view, "Create View"); err != nil {
+			return "", err
+		}
+	}
+	for _, view := range orderViews(views, creates) {
+		fmt.Fprintf(&b, "\n%s;\n", creates[view])
+	}
+	triggers, err := m.schemaNames(SCHEMA_TRIGGERS_QUERY, "Trigger")
+	if err != nil {
+		return "", err
+	}
+	for _, trigger := range triggers {
+		if err = m.dumpCreate(&b, "TRIGGER", trigger, "SQL Original Statement", true); err != nil {
+			return "", err
+		}
+	}
+	routines, err := m.query(SCHEMA_ROUTINES_QUERY, nil)
+	if err != nil {
+		return "", err
+	}
+	for _, routine := range routines {
+		kind := strings.ToUpper(getString(routine["type"]))
+		if len(kind) < 1 {
+			continue
+		}
+		column := "Create " + kind[:1] + strings.ToLower(kind[1:])
+		if err = m.dumpCreate(&b, kind, getString(routine["name"]), column, true); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+// LoadSchema bootstraps a new database from a schema dumped by DumpSchema (see
+// WithSchemaDump) and records the migrations listed in the dump as having been
+// run, as a single batch. Migrations in the dump that are not in the source are
+// ignored with a warning. It refuses to load the schema into a database in which
+// migrations have already been run.
+func (m *Migration) LoadSchema(path string) (message string, err error) {
+	if err = m.checkSchemaDialect(); err != nil {
+		return
+	}
+	if m.dryRun {
+		err = errors.New("the schema cannot be loaded in dry-run mode")
+		return
+	}
+	dump, err := GetFileContents(path)
+	if err != nil {
+		return
+	}
+	err = m.withLock(func() error {
+		m.direction = true
+		if err := m.prepare(); err != nil {
+			return err
+		}
+		if err := m.checkFresh(); err != nil {
+			return err
+		}
+		for _, statement := range getStatements(m.dialect, dump) {
+			if _, err := m.database.Exec(statement, nil); err != nil {
+				return err
+			}
+		}
+		marked, err := m.markMigrated(dump)
+		message = fmt.Sprintf("Loaded the schema from '%s' with %d migrations", path, marked)
+		return err
+	})
+	return
+}
+
+// WithSchemaDump writes the schema (see DumpSchema) to the file at the path after
+// each successful run of the migrations, so that an up-to-date snapshot can be
+// reviewed and committed. It is only supported for MySQL.
+func WithSchemaDump(path string) Option {
+	return func(m *Migration) {
+		m.schemaPath = path
+	}
+}
+
+// writeSchema writes the schema dump, if one has been asked for
+func (m *Migration) writeSchema() error {
+	if len(m.schemaPath) < 1 || m.dryRun {
+		return nil
+	}
+	dump, err := m.DumpSchema()
+	if err == nil {
+		err = os.WriteFile(m.schemaPath, []byte(dump), Permission)
+	}
+	if err != nil {
+		return fmt.Errorf("the migrations were run but the schema could not be dumped: %w", err)
+	}
+	return nil
+}
+
+func (m *Migration) checkSchemaDialect() error {
+	if m.dialect.Name() != MySQL.Name() {
+		return fmt.Errorf("schema dumps are not supported for %s", m.dialect.Name())
+	}
+	return nil
+}
+
+func (m *Migration) checkFresh() error {
+	rows, err := m.query(SCHEMA_MIGRATED_QUERY, nil)
+	if err != nil {
+		return err
+	}
+	if total, ok := rows[0]["total"].(int64); !ok || total > 0 {
+		return errors.New("the schema can only be loaded into a database in which no migrations have been run")
+	}
+	return nil
+}
+
+func (m *Migration) dumpMigrated(b *strings.Builder) error {
+	hasTable, err := m.checkHasTable()
+	if err != nil || !hasTable {
+		return err
+	}
+	rows, err := m.query(SCHEMA_APPLIED_QUERY, nil)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		fmt.Fprintf(b, "%s %s %s\n", MIGRATED_MARKER, getString(row["name"]), getString(row["checksum"]))
+	}
+	return nil
+}
+
+// markMigrated records the migrations listed in the dump as having been run
+func (m *Migration) markMigrated(dump string) (marked int, err error) {
+	inSource := make(map[string]bool)
+	for _, name := range m.files {
+		inSource[name] = true
+	}
+	batchID, err := m.nextBatch()
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(dump, "\n") {
+		if !strings.HasPrefix(line, MIGRATED_MARKER) {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, MIGRATED_MARKER))
+		if len(fields) < 1 {
+			continue
+		}
+		if !inSource[fields[0]] {
+			log.Printf("warning: migration '%s' in the schema was not found and will be ignored", fields[0])
+			continue
+		}
+		var sum interface{}
+		if len(fields) > 1 {
+			sum = fields[1]
+		}
+		if _, err = m.exec(SCHEMA_MARK_QUERY, []interface{}{batchID, sum, fields[0]}); err != nil {
+			return
+		}
+		marked++
+	}
+	return
+}
+
+// schemaTables returns the tables, ordered so that each comes after the tables
+// its foreign keys reference, and the views
+func (m *Migration) schemaTables() (tables, views []string, err error) {
+	rows, err := m.query(SCHEMA_TABLES_QUERY, nil)
+	if err != nil {
+		return
+	}
+	for _, row := range rows {
+		name := getString(row["name"])
+		if getString(row["type"]) == "VIEW" {
+			views = append(views, name)
+		} else if len(m.tableSchema) > 0 || !m.isBookkeeping(name) {
+			tables = append(tables, name)
+		}
+	}
+	references, err := m.query(SCHEMA_REFERENCES_QUERY, nil)
+	if err != nil {
+		return
+	}
+	return orderTables(tables, references), views, nil
+}
+
+// orderTables sorts the tables by name and then moves each after the tables it
+// references. Tables that reference each other are left in order of name.
+func orderTables(tables []string, references []map[string]interface{}) []string {
+	sort.Strings(tables)
+	referenced := make(map[string][]string)
+	for _, row := range references {
+		name, other := getString(row["name"]), getString(row["referenced"])
+		if name != other {
+			referenced[name] = append(referenced[name], other)
+		}
+	}
+	ordered := make([]string, 0, len(tables))
+	visited := make(map[string]bool)
+	var visit func(table string)
+	visit = func(table string) {
+		if visited[table] {
+			return
+		}
+		visited[table] = true
+		others := referenced[table]
+		sort.Strings(others)
+		for _, other := range others {
+			visit(other)
+		}
+		ordered = append(ordered, table)
+	}
+	exists := make(map[string]bool)
+	for _, table := range tables {
+		exists[table] = true
+	}
+	for _, table := range tables {
+		visit(table)
+	}
+	// Referenced tables that are not in the schema are not dumped
+	result := make([]string, 0, len(tables))
+	for _, table := range ordered {
+		if exists[table] {
+			result = append(result, table)
+		}
+	}
+	return result
+}
+
+// orderViews orders the views as orderTables does, moving each after the views
+// named in its definition. A name that is only a column or a string is taken as
+// a reference too, which at worst orders views that did not need it.
+func orderViews(views []string, creates map[string]string) []string {
+	exists := make(map[string]bool)
+	for _, view := range views {
+		exists[view] = true
+	}
+	references := make([]map[string]interface{}, 0)
+	for _, view := range views {
+		named := make(map[string]bool)
+		for _, match := range identifier.FindAllStringSubmatch(creates[view], -1) {
+			name := match[0]
+			if strings.HasPrefix(name, "`") {
+				name = strings.ReplaceAll(match[1], "``", "`")
+			}
+			if exists[name] && !named[name] {
+				named[name] = true
+				references = append(references, map[string]interface{}{"name": view, "referenced": name})
+			}
+		}
+	}
+	return orderTables(views, references)
+}
+
+// schemaNames lists the values of the column, in order
+func (m *Migration) schemaNames(query, column string) ([]string, error) {
+	rows, err := m.query(query, nil)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(rows))
+	for _, row := range rows {
+		names = append(names, getString(row[column]))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// dumpCreate writes the statement that creates the object. Objects with bodies
+// are written between DELIMITER lines so that the dump can be split again.
+func (m *Migration) dumpCreate(b *strings.Builder, kind, name, column string, body bool) error {
+	statement, err := m.showCreate(kind, name, column)
+	if err != nil {
+		return err
+	}
+	if body {
+		fmt.Fprintf(b, "\nDELIMITER %s\n%s%s\nDELIMITER ;\n", SCHEMA_DELIMITER, statement, SCHEMA_DELIMITER)
+		return nil
+	}
+	fmt.Fprintf(b, "\n%s;\n", statement)
+	return nil
+}
+
+// showCreate returns the statement that creates the object, without its
+// AUTO_INCREMENT counter or definer
+func (m *Migration) showCreate(kind, name, column string) (string, error) {
+	query := fmt.Sprintf("SHOW CREATE %s `%s`", kind, strings.ReplaceAll(name, "`", "``"))
+	rows, err := m.database.QueryRaw(query, nil)
+	if err != nil {
+		return "", err
+	}
+	if len(rows) < 1 {
+		return "", fmt.Errorf("could not dump %s '%s'", strings.ToLower(kind), name)
+	}
+	statement := getString(rows[0][column])
+	return definer.ReplaceAllString(autoIncrement.ReplaceAllString(statement, ""), ""), nil
+}
+
+// getString returns a string column, which may also have been scanned as bytes;
+// NULL is returned as an empty string
+func getString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
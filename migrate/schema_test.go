@@ -0,0 +1,166 @@
+package migrate
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	TEST_DUMP_SCHEMA = "test_mysql_mig_dump"
+	TEST_LOAD_SCHEMA = "test_mysql_mig_load"
+	TEST_GADGETS     = `CREATE TABLE gadgets (
+	id INT(6) UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+	name VARCHAR(255) NOT NULL
+);`
+	TEST_ACCESSORIES = `CREATE TABLE accessories (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	gadget_id INT(6) UNSIGNED NOT NULL,
+	FOREIGN KEY (gadget_id) REFERENCES gadgets (id)
+);
+INSERT INTO gadgets (name) VALUES ('sprocket');
+INSERT INTO accessories (gadget_id) VALUES (1);`
+	TEST_GADGET_VIEW    = "CREATE VIEW gadget_names AS SELECT name FROM gadgets;"
+	TEST_GADGET_TRIGGER = `DELIMITER //
+CREATE TRIGGER name_gadgets BEFORE INSERT ON gadgets FOR EACH ROW
+BEGIN
+	SET NEW.name = UPPER(NEW.name);
+END//
+DELIMITER ;`
+	TEST_NESTED_VIEWS = `CREATE VIEW v_b AS SELECT name FROM gadget_names;
+CREATE VIEW v_a AS SELECT name FROM v_b;`
+)
+
+func TestSchemaDump(t *testing.T) {
+	dumpConn := getSchemaConnection(t, TEST_DUMP_SCHEMA)
+	loadConn := getSchemaConnection(t, TEST_LOAD_SCHEMA)
+	path := filepath.Join(t.TempDir(), "schema.sql")
+	source := schemaSource(t)
+	if _, err := New(FromDB(dumpConn), source, WithSchemaDump(path)).MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := GetFileContents(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSchemaDump(t, contents)
+	if dump, err := New(FromDB(dumpConn), source).DumpSchema(); err != nil || dump != contents {
+		t.Errorf("expected the dump to be the same when it is taken again (%v)", err)
+	}
+
+	m := New(FromDB(loadConn), source)
+	if _, err = m.LoadSchema(path); err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if !status.Migrated {
+			t.Errorf("expected '%s' to have been recorded as migrated", status.Name)
+		}
+	}
+	if dump, err := m.DumpSchema(); err != nil || dump != contents {
+		t.Errorf("expected the loaded schema to match the dump (%v):\n%s", err, dump)
+	}
+	if _, err = m.LoadSchema(path); err == nil {
+		t.Errorf("expected the schema not to be loaded into a database that has been migrated")
+	}
+}
+
+func checkSchemaDump(t *testing.T, dump string) {
+	for _, name := range []string{"create_gadgets_table.1", "create_accessories_table.2", "create_gadget_view.3", "create_gadget_trigger.4", "create_nested_views.5"} {
+		if !strings.Contains(dump, fmt.Sprintf("%s %s ", MIGRATED_MARKER, name)) {
+			t.Errorf("expected the dump to record migration '%s'", name)
+		}
+	}
+	if strings.Contains(dump, "AUTO_INCREMENT=") {
+		t.Errorf("expected AUTO_INCREMENT counters to have been stripped")
+	}
+	if strings.Contains(dump, "CREATE TABLE `migrations`") {
+		t.Errorf("expected the migrations table to have been left out")
+	}
+	gadgets, accessories := strings.Index(dump, "CREATE TABLE `gadgets`"), strings.Index(dump, "CREATE TABLE `accessories`")
+	if gadgets < 0 || accessories < gadgets {
+		t.Errorf("expected 'gadgets' to be created before 'accessories', which references it")
+	}
+	names, b, a := strings.Index(dump, "VIEW `gadget_names`"), strings.Index(dump, "VIEW `v_b`"), strings.Index(dump, "VIEW `v_a`")
+	if names < 0 || b < names || a < b {
+		t.Errorf("expected the views to be created in the order gadget_names, v_b, v_a, which select from each other")
+	}
+	for _, expected := range []string{"DELIMITER " + SCHEMA_DELIMITER, "name_gadgets"} {
+		if !strings.Contains(dump, expected) {
+			t.Errorf("expected the dump to contain %q", expected)
+		}
+	}
+}
+
+func TestOrderTables(t *testing.T) {
+	references := []map[string]interface{}{
+		{"name": "a", "referenced": "c"},
+		{"name": "c", "referenced": "b"},
+		{"name": "b", "referenced": "b"},
+		{"name": "d", "referenced": "elsewhere"},
+	}
+	ordered := orderTables([]string{"d", "c", "b", "a"}, references)
+	if strings.Join(ordered, ",") != "b,c,a,d" {
+		t.Errorf("expected the tables to be ordered b,c,a,d, got %v", ordered)
+	}
+}
+
+func TestOrderViews(t *testing.T) {
+	creates := map[string]string{
+		"v_a":      "CREATE VIEW `v_a` AS select `v_b`.`name` AS `name` from `schema`.`v_b`",
+		"v_b":      "CREATE VIEW `v_b` AS select `name` from `gadgets` join `odd``view` using (`id`)",
+		"odd`view": "CREATE VIEW `odd``view` AS SELECT id FROM gadgets",
+		"v_c":      "CREATE VIEW v_c AS SELECT name FROM v_a",
+	}
+	ordered := orderViews([]string{"odd`view", "v_a", "v_b", "v_c"}, creates)
+	if strings.Join(ordered, ",") != "odd`view,v_b,v_a,v_c" {
+		t.Errorf("expected the views to be ordered odd`view,v_b,v_a,v_c, got %v", ordered)
+	}
+}
+
+func TestSchemaDialect(t *testing.T) {
+	m := New(FromDB(nil), MakeMemorySource(), WithDialect(SQLite))
+	if _, err := m.DumpSchema(); err == nil {
+		t.Errorf("expected schema dumps not to be supported for SQLite")
+	}
+}
+
+func schemaSource(t *testing.T) *MemorySource {
+	source := MakeMemorySource()
+	for i, migration := range [][3]string{
+		{"create_gadgets_table", TEST_GADGETS, "DROP TABLE gadgets;"},
+		{"create_accessories_table", TEST_ACCESSORIES, "DROP TABLE accessories;"},
+		{"create_gadget_view", TEST_GADGET_VIEW, "DROP VIEW gadget_names;"},
+		{"create_gadget_trigger", TEST_GADGET_TRIGGER, "DROP TRIGGER name_gadgets;"},
+		{"create_nested_views", TEST_NESTED_VIEWS, "DROP VIEW v_a;\nDROP VIEW v_b;"},
+	} {
+		if err := source.Add(migration[0], int64(i+1), migration[1], migration[2]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return source
+}
+
+// getSchemaConnection creates a schema of its own for the test, which is dropped
+// when the test finishes
+func getSchemaConnection(t *testing.T, schema string) *sql.DB {
+	db.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s", schema), nil)
+	if _, err := db.Exec(fmt.Sprintf("CREATE SCHEMA %s", schema), nil); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := sql.Open("mysql", fmt.Sprintf("root:%s@tcp(127.0.0.1:%s)/%s", ts.Password(), ts.HostPortStr(), schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		db.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s", schema), nil)
+	})
+	return conn
+}
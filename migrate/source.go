@@ -0,0 +1,252 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	DIRECTION_MARKER = "[DIRECTION]"
+)
+
+type (
+	// Source provides the migrations. Migrations are named {name}.{timestamp} and
+	// are run in the order of their timestamps.
+	Source interface {
+		// List returns the names of the migrations, in any order. It returns an
+		// error wrapping fs.ErrNotExist if the source does not exist yet.
+		List() ([]string, error)
+		// Read returns the SQL that runs the named migration and the SQL that
+		// reverses it
+		Read(name string) (up, down string, err error)
+	}
+
+	// WritableSource is a Source that new migrations can be added to by Create
+	WritableSource interface {
+		Source
+		// Write adds the migration with the contents of a migration file, which has
+		// a [DIRECTION] line between its up and down SQL, and returns its location
+		Write(name, contents string) (string, error)
+	}
+
+	// FileSource reads and writes migration files, {name}.{timestamp}.sql, in a
+	// directory, which is created when migrations are run if it does not exist.
+	// Migrations archived by Squash are moved to its archive subdirectory.
+	FileSource struct {
+		path string
+	}
+
+	// FSSource reads migration files, {name}.{timestamp}.sql, from an fs.FS such
+	// as an embed.FS. It is read-only.
+	FSSource struct {
+		fsys fs.FS
+	}
+
+	// MemorySource keeps migrations in memory
+	MemorySource struct {
+		migrations map[string][2]string
+		archived   map[string][2]string
+	}
+
+	// initialiser is implemented by sources that need setting up before migrations
+	// are run, but not in dry-run mode
+	initialiser interface {
+		init() error
+	}
+)
+
+// MakeFileSource creates a source for the migration files in the directory
+func MakeFileSource(path string) *FileSource {
+	return &FileSource{path: path}
+}
+
+func (s *FileSource) List() ([]string, error) {
+	exists, err := DirExists(s.path)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("migrations directory '%s' does not exist: %w", s.path, fs.ErrNotExist)
+	}
+	return listFS(os.DirFS(s.path))
+}
+
+func (s *FileSource) Read(name string) (up, down string, err error) {
+	return readFS(os.DirFS(s.path), name)
+}
+
+func (s *FileSource) Write(name, contents string) (string, error) {
+	fullPath := fmt.Sprintf("%s/%s.sql", s.path, name)
+	if err := os.WriteFile(fullPath, []byte(contents), Permission); err != nil {
+		return "", err
+	}
+	os.Chmod(fullPath, Permission)
+	return fullPath, nil
+}
+
+func (s *FileSource) Archive(name string) error {
+	archive := fmt.Sprintf("%s/%s", s.path, ARCHIVE_DIR)
+	if err := os.MkdirAll(archive, Permission); err != nil {
+		return err
+	}
+	return os.Rename(fmt.Sprintf("%s/%s.sql", s.path, name), fmt.Sprintf("%s/%s.sql", archive, name))
+}
+
+func (s *FileSource) Remove(name string) error {
+	return os.Remove(fmt.Sprintf("%s/%s.sql", s.path, name))
+}
+
+// init creates the directory if it does not exist
+func (s *FileSource) init() error {
+	exists, err := DirExists(s.path)
+	if err != nil || exists {
+		return err
+	}
+	return os.Mkdir(s.path, Permission)
+}
+
+// MakeFSSource creates a source for the migration files at the root of fsys. Use
+// fs.Sub for migrations kept in a subdirectory.
+func MakeFSSource(fsys fs.FS) *FSSource {
+	return &FSSource{fsys: fsys}
+}
+
+func (s *FSSource) List() ([]string, error) {
+	return listFS(s.fsys)
+}
+
+func (s *FSSource) Read(name string) (up, down string, err error) {
+	return readFS(s.fsys, name)
+}
+
+// MakeMemorySource creates an empty in-memory source
+func MakeMemorySource() *MemorySource {
+	return &MemorySource{migrations: make(map[string][2]string), archived: make(map[string][2]string)}
+}
+
+// Add adds a migration named {name}.{timestamp}
+func (s *MemorySource) Add(name string, timestamp int64, up, down string) error {
+	fullname := fmt.Sprintf("%s.%d", name, timestamp)
+	if _, ok := s.migrations[fullname]; ok {
+		return fmt.Errorf("migration '%s' already exists", fullname)
+	}
+	s.migrations[fullname] = [2]string{up, down}
+	return nil
+}
+
+func (s *MemorySource) List() ([]string, error) {
+	names := make([]string, 0, len(s.migrations))
+	for name := range s.migrations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *MemorySource) Read(name string) (up, down string, err error) {
+	migration, ok := s.migrations[name]
+	if !ok {
+		return "", "", fmt.Errorf("migration '%s' not found: %w", name, fs.ErrNotExist)
+	}
+	return migration[0], migration[1], nil
+}
+
+func (s *MemorySource) Write(name, contents string) (string, error) {
+	up, down, err := parseMigration(name, contents)
+	if err != nil {
+		return "", err
+	}
+	s.migrations[name] = [2]string{up, down}
+	return name, nil
+}
+
+func (s *MemorySource) Archive(name string) error {
+	migration, ok := s.migrations[name]
+	if !ok {
+		return fmt.Errorf("migration '%s' not found: %w", name, fs.ErrNotExist)
+	}
+	s.archived[name] = migration
+	delete(s.migrations, name)
+	return nil
+}
+
+func (s *MemorySource) Remove(name string) error {
+	if _, ok := s.migrations[name]; !ok {
+		return fmt.Errorf("migration '%s' not found: %w", name, fs.ErrNotExist)
+	}
+	delete(s.migrations, name)
+	return nil
+}
+
+// listFS lists the .sql files in fsys and its subdirectories, other than the
+// archive directory, without their extension
+func listFS(fsys fs.FS) ([]string, error) {
+	names := make([]string, 0)
+	err := fs.WalkDir(fsys, ".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && path == ARCHIVE_DIR {
+			return fs.SkipDir
+		}
+		if !entry.IsDir() && len(entry.Name()) > 4 && strings.HasSuffix(entry.Name(), ".sql") {
+			names = append(names, strings.TrimSuffix(entry.Name(), ".sql"))
+		}
+		return nil
+	})
+	return names, err
+}
+
+func readFS(fsys fs.FS, name string) (up, down string, err error) {
+	contents, err := fs.ReadFile(fsys, name+".sql")
+	if err != nil {
+		return "", "", err
+	}
+	return parseMigration(name, string(contents))
+}
+
+// parseMigration splits the contents of a migration file on its [DIRECTION] line
+func parseMigration(name, contents string) (up, down string, err error) {
+	parts := strings.Split(contents, DIRECTION_MARKER)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("migration '%s' should have exactly one %s line, found %d", name, DIRECTION_MARKER, len(parts)-1)
+	}
+	return parts[0], parts[1], nil
+}
+
+// listFiles lists the migrations in the source, keyed by their timestamps
+func (m *Migration) listFiles() (files map[int]string, keys []int, err error) {
+	names, err := m.source.List()
+	if err != nil {
+		return nil, nil, err
+	}
+	files = make(map[int]string)
+	keys = make([]int, 0, len(names))
+	for _, name := range names {
+		key, err := getKey(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		files[key] = name
+		keys = append(keys, key)
+	}
+	return files, keys, nil
+}
+
+// getKey parses the timestamp of the migration
+func getKey(name string) (int, error) {
+	fileSplit := strings.Split(name, ".")
+	if len(fileSplit) != 2 {
+		return 0, errors.New("Migration name is malformed: Should be {name}.{timestamp}.sql")
+	}
+	key, err := strconv.Atoi(fileSplit[1])
+	if err != nil {
+		return 0, errors.New("could not parse migration file timestamp")
+	}
+	return key, nil
+}
@@ -0,0 +1,71 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMemorySource(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	source := MakeMemorySource()
+	if err = source.Add("create_gadgets_table", 1, "CREATE TABLE gadgets (id INT PRIMARY KEY, name VARCHAR(255) NOT NULL);", "DROP TABLE gadgets;"); err != nil {
+		t.Fatal(err)
+	}
+	if err = source.Add("create_gadgets_table", 1, "", ""); err == nil {
+		t.Errorf("expected an error for a migration that already exists")
+	}
+	if _, err = MakeWithSource(db, source).MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, path, map[string]bool{"create_gadgets_table.1": true})
+
+	// Migrations created in a writable source are kept there
+	_, fullname, _, err := MakeWithSource(db, source).Create("add_colour")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = source.Read(fullname); err != nil {
+		t.Errorf("expected the created migration to be in the source: %s", err.Error())
+	}
+	if _, err = MakeWithSource(db, source).MigrateDown(); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, path, map[string]bool{"create_gadgets_table.1": false})
+}
+
+func TestSourceErrors(t *testing.T) {
+	if _, err := MakeFileSource("/no/such/directory").List(); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist for a missing directory, got %v", err)
+	}
+	if _, _, err := MakeMemorySource().Read("missing.1"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist for a missing migration, got %v", err)
+	}
+	source := MakeFSSource(fstest.MapFS{"no_direction.1.sql": {Data: []byte("SELECT 1;")}})
+	if _, _, err := source.Read("no_direction.1"); err == nil {
+		t.Errorf("expected an error for a file without a [DIRECTION] line")
+	}
+	for _, name := range []string{"no_timestamp", "too.many.dots", "bad.timestamp"} {
+		if _, err := getKey(name); err == nil {
+			t.Errorf("expected an error for the name '%s'", name)
+		}
+	}
+}
+
+func TestChecksumMatchesFile(t *testing.T) {
+	up, down, err := parseMigration("create_gadgets_table.1", TEST_GADGETS_TABLE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(TEST_GADGETS_TABLE))
+	if checksum(up, down) != hex.EncodeToString(sum[:]) {
+		t.Errorf("expected the checksum to match that of the whole file")
+	}
+}
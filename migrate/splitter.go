@@ -0,0 +1,268 @@
+package migrate
+
+import (
+	"strings"
+)
+
+const (
+	DEFAULT_DELIMITER = ";"
+)
+
+type (
+	// splitter is a small lexer that splits SQL into statements on the current
+	// delimiter, ignoring delimiters inside string literals, quoted identifiers and
+	// comments. The rules of the dialect decide which syntax is recognised.
+	splitter struct {
+		rules      splitRules
+		sql        string
+		pos        int
+		delimiter  string
+		current    strings.Builder
+		hasContent bool
+		statements []string
+		word       strings.Builder
+		head       []string // the first words of the current statement
+		depth      int      // the depth of BEGIN and CASE blocks in a trigger
+	}
+
+	// splitRules describes the syntax of a dialect that matters when splitting
+	splitRules struct {
+		hashComments     bool // # starts a comment
+		backslashEscapes bool // backslashes escape characters in strings
+		backticks        bool // backticks quote identifiers
+		delimiters       bool // DELIMITER lines change the delimiter, as in the mysql client
+		dollarQuotes     bool // $$ and $tag$ quote strings, as in PostgreSQL
+		triggers         bool // semicolons inside CREATE TRIGGER ... BEGIN ... END do not end the statement
+	}
+)
+
+var (
+	mysqlRules    = splitRules{hashComments: true, backslashEscapes: true, backticks: true, delimiters: true}
+	postgresRules = splitRules{dollarQuotes: true}
+	sqliteRules   = splitRules{backticks: true, triggers: true}
+)
+
+// splitStatements splits MySQL into its individual statements, without their
+// delimiters. Statements that contain nothing but comments are dropped.
+func splitStatements(sql string) []string {
+	return splitWith(mysqlRules, sql)
+}
+
+func splitWith(rules splitRules, sql string) []string {
+	s := &splitter{rules: rules, sql: sql, delimiter: DEFAULT_DELIMITER, statements: make([]string, 0)}
+	s.split()
+	return s.statements
+}
+
+func (s *splitter) split() {
+	for s.pos < len(s.sql) {
+		if s.rules.delimiters && s.atLineStart() && s.delimiterCommand() {
+			continue
+		}
+		c := s.sql[s.pos]
+		if !isWordChar(c) {
+			s.endWord()
+		}
+		switch {
+		case strings.HasPrefix(s.sql[s.pos:], s.delimiter):
+			if s.inTrigger() {
+				s.current.WriteString(s.delimiter)
+				s.pos += len(s.delimiter)
+				continue
+			}
+			s.pos += len(s.delimiter)
+			s.flush()
+		case c == '\'' || c == '"' || (c == '`' && s.rules.backticks):
+			s.quoted(c)
+		case c == '$' && s.rules.dollarQuotes && s.dollarQuoted():
+			continue
+		case strings.HasPrefix(s.sql[s.pos:], "/*"):
+			s.blockComment()
+		case (c == '#' && s.rules.hashComments) || s.lineCommentStart():
+			s.lineComment()
+		default:
+			if !isSpace(c) {
+				s.hasContent = true
+			}
+			if isWordChar(c) {
+				s.word.WriteByte(c)
+			}
+			s.current.WriteByte(c)
+			s.pos++
+		}
+	}
+	s.endWord()
+	s.flush()
+}
+
+// flush ends the current statement
+func (s *splitter) flush() {
+	if s.hasContent {
+		s.statements = append(s.statements, strings.TrimSpace(s.current.String()))
+	}
+	s.current.Reset()
+	s.hasContent = false
+	s.head = nil
+	s.depth = 0
+}
+
+// endWord notes the word that has just ended, to follow the blocks of triggers
+func (s *splitter) endWord() {
+	if s.word.Len() < 1 {
+		return
+	}
+	word := strings.ToUpper(s.word.String())
+	s.word.Reset()
+	if !s.rules.triggers {
+		return
+	}
+	if len(s.head) < 3 {
+		s.head = append(s.head, word)
+	}
+	switch word {
+	case "BEGIN", "CASE":
+		s.depth++
+	case "END":
+		s.depth--
+	}
+}
+
+// inTrigger reports whether the position is inside the body of a trigger
+func (s *splitter) inTrigger() bool {
+	if !s.rules.triggers || s.depth < 1 || len(s.head) < 2 || s.head[0] != "CREATE" {
+		return false
+	}
+	return s.head[1] == "TRIGGER" || (len(s.head) > 2 && (s.head[1] == "TEMP" || s.head[1] == "TEMPORARY") && s.head[2] == "TRIGGER")
+}
+
+// dollarQuoted consumes a dollar-quoted string, $$...$$ or $tag$...$tag$, and
+// reports whether there was one at the position
+func (s *splitter) dollarQuoted() bool {
+	if s.pos > 0 && isWordChar(s.sql[s.pos-1]) {
+		// A dollar sign inside an identifier
+		return false
+	}
+	end := s.pos + 1
+	for end < len(s.sql) && isWordChar(s.sql[end]) && !(end == s.pos+1 && s.sql[end] >= '0' && s.sql[end] <= '9') {
+		end++
+	}
+	if end >= len(s.sql) || s.sql[end] != '$' {
+		// A positional parameter such as $1
+		return false
+	}
+	tag := s.sql[s.pos : end+1]
+	closing := strings.Index(s.sql[end+1:], tag)
+	if closing < 0 {
+		closing = len(s.sql)
+	} else {
+		closing += end + 1 + len(tag)
+	}
+	s.hasContent = true
+	s.current.WriteString(s.sql[s.pos:closing])
+	s.pos = closing
+	return true
+}
+
+// quoted consumes a string literal or quoted identifier, including its quotes
+func (s *splitter) quoted(quote byte) {
+	s.hasContent = true
+	s.current.WriteByte(quote)
+	s.pos++
+	for s.pos < len(s.sql) {
+		c := s.sql[s.pos]
+		s.current.WriteByte(c)
+		s.pos++
+		if c == '\\' && s.rules.backslashEscapes && quote != '`' && s.pos < len(s.sql) {
+			// Backslash escapes the next character in strings
+			s.current.WriteByte(s.sql[s.pos])
+			s.pos++
+			continue
+		}
+		if c == quote {
+			if s.pos < len(s.sql) && s.sql[s.pos] == quote {
+				// A doubled quote is an escaped quote
+				s.current.WriteByte(quote)
+				s.pos++
+				continue
+			}
+			return
+		}
+	}
+}
+
+// blockComment consumes a /* */ comment. Executable comments (/*! */) and
+// optimizer hints (/*+ */) are sent to the server, so they count as content.
+func (s *splitter) blockComment() {
+	if s.pos+2 < len(s.sql) && (s.sql[s.pos+2] == '!' || s.sql[s.pos+2] == '+') {
+		s.hasContent = true
+	}
+	end := strings.Index(s.sql[s.pos+2:], "*/")
+	if end < 0 {
+		s.current.WriteString(s.sql[s.pos:])
+		s.pos = len(s.sql)
+		return
+	}
+	end += s.pos + 4
+	s.current.WriteString(s.sql[s.pos:end])
+	s.pos = end
+}
+
+// lineCommentStart reports whether the position starts a -- comment, which MySQL
+// requires to be followed by whitespace or the end of the line
+func (s *splitter) lineCommentStart() bool {
+	if !strings.HasPrefix(s.sql[s.pos:], "--") {
+		return false
+	}
+	return s.pos+2 >= len(s.sql) || isSpace(s.sql[s.pos+2])
+}
+
+// lineComment consumes a comment up to, but not including, the end of the line
+func (s *splitter) lineComment() {
+	end := strings.IndexByte(s.sql[s.pos:], '\n')
+	if end < 0 {
+		end = len(s.sql) - s.pos
+	}
+	s.current.WriteString(s.sql[s.pos : s.pos+end])
+	s.pos += end
+}
+
+func (s *splitter) atLineStart() bool {
+	for i := s.pos - 1; i >= 0; i-- {
+		if s.sql[i] == '\n' {
+			return true
+		}
+		if s.sql[i] != ' ' && s.sql[i] != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// delimiterCommand handles a DELIMITER line, which ends the current statement
+// and changes the delimiter for the statements that follow
+func (s *splitter) delimiterCommand() bool {
+	start := s.pos
+	for start < len(s.sql) && (s.sql[start] == ' ' || s.sql[start] == '\t') {
+		start++
+	}
+	end := strings.IndexByte(s.sql[start:], '\n')
+	if end < 0 {
+		end = len(s.sql) - start
+	}
+	fields := strings.Fields(s.sql[start : start+end])
+	if len(fields) < 2 || !strings.EqualFold(fields[0], "DELIMITER") {
+		return false
+	}
+	s.flush()
+	s.delimiter = fields[1]
+	s.pos = start + end
+	return true
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
@@ -0,0 +1,69 @@
+package migrate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	cases := map[string][]string{
+		"CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);": {
+			"CREATE TABLE a (id INT)",
+			"CREATE TABLE b (id INT)",
+		},
+		"INSERT INTO a VALUES ('x;y', \"it's; fine\", 'escaped \\' quote;', 'doubled '' quote;');": {
+			"INSERT INTO a VALUES ('x;y', \"it's; fine\", 'escaped \\' quote;', 'doubled '' quote;')",
+		},
+		"SELECT `odd;name` FROM a; SELECT 1": {
+			"SELECT `odd;name` FROM a",
+			"SELECT 1",
+		},
+		"-- a comment; with a semicolon\nSELECT 1; # another; comment\n/* block; comment */ SELECT 2;": {
+			"-- a comment; with a semicolon\nSELECT 1",
+			"# another; comment\n/* block; comment */ SELECT 2",
+		},
+		"SELECT 1 --not a comment;\n": {
+			"SELECT 1 --not a comment",
+		},
+		"-- only comments\n/* here */\n": {},
+		"/*!40101 SET NAMES utf8 */;": {
+			"/*!40101 SET NAMES utf8 */",
+		},
+		MIGRATE_DEFAULT: {},
+	}
+	for sql, expected := range cases {
+		if statements := splitStatements(sql); !reflect.DeepEqual(statements, expected) {
+			t.Errorf("unexpected statements for %q:\n%q", sql, statements)
+		}
+	}
+}
+
+func TestSplitStatementsDelimiter(t *testing.T) {
+	sql := `DROP PROCEDURE IF EXISTS count_gadgets;
+DELIMITER $$
+CREATE PROCEDURE count_gadgets()
+BEGIN
+	SELECT COUNT(*) FROM gadgets;
+END$$
+DELIMITER ;
+CALL count_gadgets();
+`
+	expected := []string{
+		"DROP PROCEDURE IF EXISTS count_gadgets",
+		"CREATE PROCEDURE count_gadgets()\nBEGIN\n\tSELECT COUNT(*) FROM gadgets;\nEND",
+		"CALL count_gadgets()",
+	}
+	if statements := splitStatements(sql); !reflect.DeepEqual(statements, expected) {
+		t.Errorf("unexpected statements:\n%q", statements)
+	}
+}
+
+func TestGetStatementsWithMarker(t *testing.T) {
+	statements := getStatements(MySQL, "-- comment only\n[STATEMENT] SELECT 1; SELECT 2;\n[STATEMENT] SELECT 3;\n")
+	if len(statements) != 2 {
+		t.Fatalf("expected the [STATEMENT] marker to be used, got %q", statements)
+	}
+	if statements[0] != " SELECT 1; SELECT 2;\n" {
+		t.Errorf("expected the statement to be passed through unchanged, got %q", statements[0])
+	}
+}
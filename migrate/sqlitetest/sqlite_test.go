@@ -0,0 +1,140 @@
+// Package sqlitetest runs the migrations against SQLite, which needs no external
+// service, outside the MySQL test harness of the migrate package.
+package sqlitetest
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/blainemoser/MySqlMigrate/migrate"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	CREATE_GADGETS = `CREATE TABLE gadgets (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(255) NOT NULL,
+	touched INTEGER DEFAULT 0
+);`
+	DROP_GADGETS   = "DROP TABLE gadgets;"
+	CREATE_TRIGGER = `CREATE TRIGGER touch_gadgets AFTER UPDATE OF name ON gadgets
+BEGIN
+	UPDATE gadgets SET touched = touched + 1 WHERE id = NEW.id;
+END;
+INSERT INTO gadgets (name) VALUES ('sprocket');
+UPDATE gadgets SET name = 'widget';`
+	DROP_TRIGGER = "DROP TRIGGER touch_gadgets; DELETE FROM gadgets;"
+	FAILING      = "CREATE TABLE doomed (id INTEGER); INSERT INTO no_such_table VALUES (1);"
+)
+
+func TestSQLite(t *testing.T) {
+	conn := open(t)
+	source := migrate.MakeMemorySource()
+	add(t, source, "create_gadgets_table", 1, CREATE_GADGETS, DROP_GADGETS)
+	add(t, source, "add_touch_trigger", 2, CREATE_TRIGGER, DROP_TRIGGER)
+	m := migrate.New(migrate.FromDB(conn), source, migrate.WithDialect(migrate.SQLite))
+	if _, err := m.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	var touched int
+	if err := conn.QueryRow("SELECT touched FROM gadgets WHERE name = 'widget'").Scan(&touched); err != nil {
+		t.Fatal(err)
+	}
+	if touched != 1 {
+		t.Errorf("expected the trigger to have run once, got %d", touched)
+	}
+	checkMigrated(t, m, map[string]bool{"create_gadgets_table.1": true, "add_touch_trigger.2": true})
+	drifts, err := m.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifts) > 0 {
+		t.Errorf("expected no drift, got %v", drifts)
+	}
+
+	if _, err = m.MigrateTo("create_gadgets_table"); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, m, map[string]bool{"create_gadgets_table.1": true, "add_touch_trigger.2": false})
+	if _, err = m.MigrateDown(); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, m, map[string]bool{"create_gadgets_table.1": false, "add_touch_trigger.2": false})
+}
+
+func TestSQLiteRollback(t *testing.T) {
+	conn := open(t)
+	source := migrate.MakeMemorySource()
+	add(t, source, "failing", 1, FAILING, "")
+	m := migrate.New(migrate.FromDB(conn), source, migrate.WithDialect(migrate.SQLite))
+	if _, err := m.MigrateUp(); err == nil {
+		t.Fatal("expected the failing migration to return an error")
+	}
+	checkMigrated(t, m, map[string]bool{"failing.1": false})
+	var tables int
+	if err := conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'doomed'").Scan(&tables); err != nil {
+		t.Fatal(err)
+	}
+	if tables != 0 {
+		t.Errorf("expected the table to have been rolled back with the migration")
+	}
+}
+
+func TestSQLiteFiles(t *testing.T) {
+	conn := open(t)
+	dir := filepath.Join(t.TempDir(), "migrations")
+	m := migrate.New(migrate.FromDB(conn), migrate.MakeFileSource(dir), migrate.WithDialect(migrate.SQLite), migrate.WithTable("schema_migrations"))
+	_, name, _, err := m.Create("empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, m, map[string]bool{name: true})
+	var tables int
+	if err = conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name IN ('migrations', 'schema_migrations')").Scan(&tables); err != nil {
+		t.Fatal(err)
+	}
+	if tables != 1 {
+		t.Errorf("expected only the schema_migrations table to have been created")
+	}
+	var version int
+	if err = conn.QueryRow("SELECT version FROM schema_migrations_version").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if version != migrate.TABLE_VERSION {
+		t.Errorf("expected version %d of the migrations table to have been recorded, got %d", migrate.TABLE_VERSION, version)
+	}
+}
+
+func open(t *testing.T) *sql.DB {
+	conn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func add(t *testing.T, source *migrate.MemorySource, name string, timestamp int64, up, down string) {
+	if err := source.Add(name, timestamp, up, down); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkMigrated(t *testing.T, m *migrate.Migration, expected map[string]bool) {
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if migrated, ok := expected[status.Name]; ok && migrated != status.Migrated {
+			t.Errorf("expected '%s' to have migrated = %t", status.Name, migrated)
+		}
+		if status.Migrated && status.AppliedAt.IsZero() {
+			t.Errorf("expected '%s' to have a time it was applied", status.Name)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+const (
+	SQUASHED_MARKER = "-- [SQUASHED]"
+	SQUASH_NAME     = "squashed_baseline"
+	SQUASH_HEADER   = "-- Baseline squashed by MySqlMigrate. Do not alter the [SQUASHED] lines; they record the migrations it replaces.\n"
+	ARCHIVE_DIR     = "archive"
+	SQUASH_DELETE   = "DELETE FROM [table] WHERE migration_id = ?"
+)
+
+// ArchivableSource is a WritableSource whose migrations can be archived, so that
+// they are no longer listed, by Squash
+type ArchivableSource interface {
+	WritableSource
+	// Archive moves the migration out of the source
+	Archive(name string) error
+	// Remove deletes the migration, as Squash does with a baseline it could not
+	// record
+	Remove(name string) error
+}
+
+// Squash replaces every migration up to and including the target (see MigrateTo)
+// with a single baseline migration, named squashed_baseline and given the
+// timestamp of the target, whose statements are those of the migrations it
+// replaces, in order. The migrations must either all have been run or all be
+// pending. The squash is recorded in the migrations table, in a single
+// transaction when there is a connection (see WithConnection), and the baseline
+// lists the migrations it replaces, so that any database that has already run
+// them records the baseline as run rather than running it. The baseline is
+// removed if the squash cannot be recorded. Once it has been, the originals are
+// archived; a FileSource moves them to its archive directory.
+func (m *Migration) Squash(target string) (message string, err error) {
+	if m.dryRun {
+		err = errors.New("migrations cannot be squashed in dry-run mode")
+		return
+	}
+	source, ok := m.source.(ArchivableSource)
+	if !ok {
+		err = fmt.Errorf("cannot squash migrations: %w", ErrReadOnly)
+		return
+	}
+	err = m.withLock(func() error {
+		m.direction = true
+		if err := m.prepare(); err != nil {
+			return err
+		}
+		if err := m.checkDirty(); err != nil {
+			return err
+		}
+		squashed, err := m.squashCandidates(target)
+		if err != nil {
+			return err
+		}
+		if _, _, err = m.squashState(target, squashed); err != nil {
+			return err
+		}
+		name, err := m.writeBaseline(source, squashed)
+		if err != nil {
+			return err
+		}
+		if _, err = m.recordSquash(name, squashed); err != nil {
+			if removeErr := source.Remove(name); removeErr != nil {
+				log.Printf("warning: the baseline '%s' could not be removed: %s\n", name, removeErr.Error())
+			}
+			return err
+		}
+		for _, original := range squashed {
+			if err = source.Archive(original); err != nil {
+				return err
+			}
+		}
+		message = fmt.Sprintf("Squashed %d migrations into '%s'", len(squashed), name)
+		return nil
+	})
+	return
+}
+
+// squashCandidates lists the migrations up to and including the target
+func (m *Migration) squashCandidates(target string) ([]string, error) {
+	present, err := m.presentRecords()
+	if err != nil {
+		return nil, err
+	}
+	index, err := targetIndex(present, target)
+	if err != nil {
+		return nil, err
+	}
+	if index < 1 {
+		return nil, errors.New("there should be at least two migrations to squash")
+	}
+	squashed := make([]string, 0, index+1)
+	for _, row := range present[:index+1] {
+		name, _, _ := getNameAndID(row)
+		if _, ok := m.funcs[name]; ok {
+			return nil, fmt.Errorf("cannot squash the Go migration '%s'", name)
+		}
+		squashed = append(squashed, name)
+	}
+	return squashed, nil
+}
+
+// writeBaseline writes the baseline for the migrations. Their statements are
+// separated with [STATEMENT] so that each is run exactly as it was.
+func (m *Migration) writeBaseline(source WritableSource, squashed []string) (string, error) {
+	key, err := getKey(squashed[len(squashed)-1])
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s.%d", SQUASH_NAME, key)
+	ups := make([]string, len(squashed))
+	downs := make([]string, len(squashed))
+	var header strings.Builder
+	header.WriteString(SQUASH_HEADER)
+	transaction := ""
+	for i, original := range squashed {
+		up, down, err := m.source.Read(original)
+		if err != nil {
+			return "", err
+		}
+		if strings.Contains(up+down, NO_TRANSACTION) {
+			transaction = "-- " + NO_TRANSACTION + "\n"
+		}
+		fmt.Fprintf(&header, "%s %s\n", SQUASHED_MARKER, original)
+		ups[i] = m.squashStatements(original, up)
+		downs[len(squashed)-1-i] = m.squashStatements(original, down)
+	}
+	contents := fmt.Sprintf("%s%s%s\n-- %s -- do not alter this line!\n%s", header.String(), transaction, strings.Join(ups, ""), DIRECTION_MARKER, strings.Join(downs, ""))
+	_, err = source.Write(name, contents)
+	return name, err
+}
+
+func (m *Migration) squashStatements(name, sql string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n-- %s\n", name)
+	for _, statement := range getStatements(m.dialect, sql) {
+		fmt.Fprintf(&b, "%s %s\n", STATEMENT_MARKER, strings.TrimSpace(statement))
+	}
+	return b.String()
+}
+
+// squashedMigrations lists the migrations replaced by a baseline
+func squashedMigrations(up string) []string {
+	squashed := make([]string, 0)
+	for _, line := range strings.Split(up, "\n") {
+		if strings.HasPrefix(line, SQUASHED_MARKER) {
+			squashed = append(squashed, strings.TrimSpace(strings.TrimPrefix(line, SQUASHED_MARKER)))
+		}
+	}
+	return squashed
+}
+
+// recordSquash replaces the records of the squashed migrations with one for the
+// baseline, in the place of the last of them, which has been run if they have
+// all been run, in a single transaction where possible (see withTransaction).
+// It reports false, and changes nothing, if none of them has been recorded.
+func (m *Migration) recordSquash(name string, squashed []string) (bool, error) {
+	records, migrated, err := m.squashState(name, squashed)
+	if err != nil || len(records) < 1 {
+		return false, err
+	}
+	if m.dryRun {
+		// Pending migrations are planned as the baseline
+		return migrated > 0, nil
+	}
+	properties, err := m.squashProperties(name, records, migrated > 0)
+	if err != nil {
+		return false, err
+	}
+	err = m.withTransaction(func(exec Executor) error {
+		for _, record := range records {
+			_, id, _ := getNameAndID(record)
+			if _, err := exec.Exec(m.bind(SQUASH_DELETE), []interface{}{id}); err != nil {
+				return err
+			}
+		}
+		_, err := exec.CreateRecord(m.table(), properties)
+		return err
+	})
+	return err == nil, err
+}
+
+// squashState returns the records of the squashed migrations and how many of
+// them have been run, which should be all or none of them
+func (m *Migration) squashState(name string, squashed []string) (records []map[string]interface{}, migrated int, err error) {
+	if !m.hasTable {
+		return nil, 0, nil
+	}
+	rows, err := m.query(STATUS_QUERY, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	names := make(map[string]bool)
+	for _, original := range squashed {
+		names[original] = true
+	}
+	records = make([]map[string]interface{}, 0, len(squashed))
+	for _, row := range rows {
+		original, _, err := getNameAndID(row)
+		if err != nil || !names[original] {
+			continue
+		}
+		run, err := getMigrated(row["migrated"])
+		if err != nil {
+			return nil, 0, err
+		}
+		if run {
+			migrated++
+		}
+		records = append(records, row)
+	}
+	if migrated > 0 && migrated < len(squashed) {
+		return nil, 0, fmt.Errorf("only %d of the %d migrations squashed into '%s' have been run; run or reverse them first", migrated, len(squashed), name)
+	}
+	return records, migrated, nil
+}
+
+// squashProperties returns the record of the baseline, which takes its timestamp
+// as its migration id, as any migration does, and the batch of the last of the
+// squashed migrations
+func (m *Migration) squashProperties(name string, records []map[string]interface{}, migrated bool) (map[string]interface{}, error) {
+	id, err := getKey(name)
+	if err != nil {
+		return nil, err
+	}
+	var lastBatch int64
+	for _, record := range records {
+		batch, err := getBatchID(record["batch_id"])
+		if err != nil {
+			return nil, err
+		}
+		if batch > lastBatch {
+			lastBatch = batch
+		}
+	}
+	properties := m.zeroDayProperties(id, name)
+	if !migrated {
+		return properties, nil
+	}
+	up, down, err := m.source.Read(name)
+	if err != nil {
+		return nil, err
+	}
+	properties["migrated"] = 1
+	properties["batch_id"] = lastBatch
+	properties["checksum"] = checksum(up, down)
+	return properties, nil
+}
+
+// seedSquash records a new baseline in the place of the migrations it replaces,
+// if they have been recorded, rather than as a new migration
+func (m *Migration) seedSquash(name string) (bool, error) {
+	if _, ok := m.funcs[name]; ok {
+		return false, nil
+	}
+	up, _, err := m.source.Read(name)
+	if err != nil {
+		return false, err
+	}
+	squashed := squashedMigrations(up)
+	if len(squashed) < 1 {
+		return false, nil
+	}
+	return m.recordSquash(name, squashed)
+}
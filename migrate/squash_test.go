@@ -0,0 +1,239 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const (
+	TEST_SQUASH_OTHER_SCHEMA = "test_mysql_mig_other"
+	TEST_SQUASH_FRESH_SCHEMA = "test_mysql_mig_fresh"
+)
+
+var testSquashMigrations = []string{
+	"CREATE TABLE gadgets (id INT(6) UNSIGNED AUTO_INCREMENT PRIMARY KEY, name VARCHAR(255) NOT NULL);\n-- [DIRECTION]\nDROP TABLE gadgets;",
+	"INSERT INTO gadgets (name) VALUES ('sprocket');\n-- [DIRECTION]\nDELETE FROM gadgets;",
+	"ALTER TABLE gadgets ADD colour VARCHAR(50) NULL;\n-- [DIRECTION]\nALTER TABLE gadgets DROP COLUMN colour;",
+}
+
+func TestSquash(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	other := getSchemaConnection(t, TEST_SQUASH_OTHER_SCHEMA)
+	fresh := getSchemaConnection(t, TEST_SQUASH_FRESH_SCHEMA)
+	names := make([]string, 0)
+	for i, content := range testSquashMigrations {
+		name, err := writeMigration(path, fmt.Sprintf("squash_%d", i+1), int64(i+1), content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if _, err = Make(db, path).MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = New(FromDB(other), MakeFileSource(path)).MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+
+	message, err := Make(db, path).Squash("squash_3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseline := SQUASH_NAME + ".3"
+	if !strings.Contains(message, baseline) {
+		t.Errorf("expected the message to name the baseline, got '%s'", message)
+	}
+	for _, name := range names {
+		if _, err = os.Stat(fmt.Sprintf("%s/%s/%s.sql", path, ARCHIVE_DIR, name)); err != nil {
+			t.Errorf("expected '%s' to have been archived: %s", name, err.Error())
+		}
+	}
+	pending, err := writeMigration(path, "squash_4", 4, "INSERT INTO gadgets (name, colour) VALUES ('widget', 'red');\n-- [DIRECTION]\nDELETE FROM gadgets WHERE name = 'widget';")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The database the squash was run on, and another that had run the
+	// originals, only run the new migration
+	for _, m := range []*Migration{Make(db, path), New(FromDB(other), MakeFileSource(path))} {
+		if _, err = m.MigrateUp(); err != nil {
+			t.Fatal(err)
+		}
+		checkSquashStatus(t, m, map[string]bool{baseline: true, pending: true})
+		if drifts, err := m.Verify(); err != nil || len(drifts) > 0 {
+			t.Errorf("expected no drift after squashing, got %v (%v)", drifts, err)
+		}
+	}
+
+	// A new database runs the baseline
+	m := New(FromDB(fresh), MakeFileSource(path))
+	if _, err = m.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	checkSquashStatus(t, m, map[string]bool{baseline: true, pending: true})
+	var total int
+	if err = fresh.QueryRow("SELECT COUNT(*) FROM gadgets WHERE colour IS NULL").Scan(&total); err != nil || total != 1 {
+		t.Errorf("expected the baseline to have inserted a gadget without a colour, found %d (%v)", total, err)
+	}
+	if _, err = m.MigrateDown(); err != nil {
+		t.Fatal(err)
+	}
+	checkSquashStatus(t, m, map[string]bool{baseline: false, pending: false})
+}
+
+func TestSquashErrors(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	for i, content := range testSquashMigrations {
+		if _, err = writeMigration(path, fmt.Sprintf("squash_%d", i+1), int64(i+1), content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = Make(db, path).MigrateUpSteps(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Make(db, path).Squash("squash_2"); err == nil {
+		t.Errorf("expected an error for migrations that have only partly been run")
+	}
+	if _, err = Make(db, path).Squash("squash_1"); err == nil {
+		t.Errorf("expected an error for a single migration")
+	}
+	_, err = MakeFS(db, fstest.MapFS{}).Squash("squash_2")
+	if !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly for a read-only source, got %v", err)
+	}
+	if _, err = os.Stat(fmt.Sprintf("%s/%s.3.sql", path, SQUASH_NAME)); !os.IsNotExist(err) {
+		t.Errorf("expected no baseline to have been written")
+	}
+}
+
+func TestSquashRepair(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	for i, content := range testSquashMigrations {
+		if _, err = writeMigration(path, fmt.Sprintf("squash_%d", i+1), int64(i+11), content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = Make(db, path).MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	// Number the migrations as earlier versions did, rather than by timestamp
+	for i := 1; i <= len(testSquashMigrations); i++ {
+		if _, err = db.Exec("UPDATE migrations SET migration_id = ? WHERE migration_id = ?", []interface{}{i, i + 10}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = Make(db, path).Squash("squash_3"); err != nil {
+		t.Fatal(err)
+	}
+	found, err := Make(db, path).Repair(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkInconsistencies(t, found, map[string]string{}, 0)
+}
+
+func TestSquashNotRecorded(t *testing.T) {
+	conn := getConnection(t)
+	defer conn.Close()
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	names := make([]string, 0)
+	for i, content := range testSquashMigrations {
+		name, err := writeMigration(path, fmt.Sprintf("squash_%d", i+1), int64(i+1), content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if _, err = Make(db, path).MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	// The record of the baseline is refused once the originals' are deleted
+	if _, err = db.Exec("CREATE TRIGGER refuse_baseline BEFORE INSERT ON migrations FOR EACH ROW BEGIN IF NEW.name LIKE '"+SQUASH_NAME+"%' THEN SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'refused'; END IF; END", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Make(db, path, WithConnection(conn)).Squash("squash_3"); err == nil {
+		t.Fatalf("expected the squash not to be recorded")
+	}
+	if _, err = os.Stat(fmt.Sprintf("%s/%s.3.sql", path, SQUASH_NAME)); !os.IsNotExist(err) {
+		t.Errorf("expected the baseline to have been removed")
+	}
+	for _, name := range names {
+		if _, err = os.Stat(fmt.Sprintf("%s/%s.sql", path, name)); err != nil {
+			t.Errorf("expected '%s' not to have been archived: %s", name, err.Error())
+		}
+		checkRecords(t, "migrations", name, 1)
+	}
+}
+
+func TestSquashedMigrations(t *testing.T) {
+	source := MakeMemorySource()
+	for i, content := range testSquashMigrations {
+		up, down, _ := parseMigration("", content)
+		if err := source.Add(fmt.Sprintf("squash_%d", i+1), int64(i+1), up, down); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := New(FromDB(nil), source)
+	name, err := m.writeBaseline(source, []string{"squash_1.1", "squash_2.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	up, down, err := source.Read(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if squashed := squashedMigrations(up); strings.Join(squashed, ",") != "squash_1.1,squash_2.2" {
+		t.Errorf("expected the baseline to list the squashed migrations, got %v", squashed)
+	}
+	statements := getStatements(MySQL, down)
+	if len(statements) != 2 || !strings.HasPrefix(statements[0], " DELETE") || !strings.HasPrefix(statements[1], " DROP") {
+		t.Errorf("expected the down statements to be reversed, got %q", statements)
+	}
+	if err = source.Archive("squash_1.1"); err != nil {
+		t.Fatal(err)
+	}
+	if names, _ := source.List(); len(names) != 3 {
+		t.Errorf("expected the archived migration not to be listed, got %v", names)
+	}
+	if err = source.Remove(name); err != nil {
+		t.Fatal(err)
+	}
+	if names, _ := source.List(); len(names) != 2 {
+		t.Errorf("expected the removed baseline not to be listed, got %v", names)
+	}
+}
+
+func checkSquashStatus(t *testing.T, m *Migration, expected map[string]bool) {
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != len(expected) {
+		t.Errorf("expected %d migrations, got %d", len(expected), len(statuses))
+	}
+	for _, status := range statuses {
+		if migrated, ok := expected[status.Name]; !ok || migrated != status.Migrated {
+			t.Errorf("expected '%s' to have migrated = %t", status.Name, migrated)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package migrate
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	STATUS_QUERY     = "SELECT * FROM [table] ORDER BY migration_id ASC"
+	TIMESTAMP_LAYOUT = "2006-01-02 15:04:05"
+)
+
+// MigrationStatus describes the state of a single migration
+type MigrationStatus struct {
+	Name        string
+	MigrationID int64
+	BatchID     int64
+	Migrated    bool
+	AppliedAt   time.Time // zero unless the migration has been run
+	FileExists  bool
+	Recorded    bool // false for files that have not been added to the migrations table yet
+	Dirty       bool // the migration failed part of the way through; see Resume and Force
+	Done        int  // the number of statements of a dirty migration that have been run
+
+	// The last run or reversal of the migration; empty for migrations that have
+	// not been run, or were run before this was recorded
+	Direction  string        // DIRECTION_UP or DIRECTION_DOWN
+	Duration   time.Duration // how long its statements took
+	Host       string        // the host that ran it
+	DBUser     string        // the database user that ran it
+	AppVersion string        // see WithAppVersion
+}
+
+// Status lists every migration, whether recorded in the migrations table or only
+// present on disk, ordered by migration id. Files that have not been recorded yet
+// are listed last. Status does not write to the database or the migrations folder.
+func (m *Migration) Status() ([]MigrationStatus, error) {
+	onDisk, err := m.filesOnDisk()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := m.recordedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]MigrationStatus, 0, len(rows)+len(m.files))
+	recorded := make(map[string]bool)
+	for _, row := range rows {
+		status, err := getStatus(row)
+		if err != nil {
+			return nil, err
+		}
+		status.FileExists = onDisk[status.Name]
+		recorded[status.Name] = true
+		result = append(result, status)
+	}
+	for _, name := range m.files {
+		if !recorded[name] {
+			result = append(result, MigrationStatus{Name: name, FileExists: true})
+		}
+	}
+	return result, nil
+}
+
+// Pending returns true if the status has a file that has yet to be run
+func (s MigrationStatus) Pending() bool {
+	return s.FileExists && !s.Migrated
+}
+
+func (m *Migration) filesOnDisk() (map[string]bool, error) {
+	result := make(map[string]bool)
+	if err := m.findFiles(); err != nil {
+		return nil, err
+	}
+	for _, name := range m.files {
+		result[name] = true
+	}
+	return result, nil
+}
+
+func (m *Migration) recordedMigrations() ([]map[string]interface{}, error) {
+	hasTable, err := m.checkHasTable()
+	if err != nil || !hasTable {
+		return nil, err
+	}
+	return m.query(STATUS_QUERY, nil)
+}
+
+func getStatus(row map[string]interface{}) (status MigrationStatus, err error) {
+	if status.Name, status.MigrationID, err = getNameAndID(row); err != nil {
+		return
+	}
+	status.Recorded = true
+	if status.BatchID, err = getBatchID(row["batch_id"]); err != nil {
+		return
+	}
+	if status.Migrated, err = getMigrated(row["migrated"]); err != nil {
+		return
+	}
+	if status.Migrated {
+		if status.AppliedAt, err = getTimestamp(row["updated_at"]); err != nil {
+			return
+		}
+	}
+	status.Direction = getString(row["direction"])
+	status.Host = getString(row["host"])
+	status.DBUser = getString(row["db_user"])
+	status.AppVersion = getString(row["app_version"])
+	if status.Duration, err = getDuration(row["duration_ms"]); err != nil {
+		return
+	}
+	if status.Dirty, err = getMigrated(row["dirty"]); err != nil || !status.Dirty {
+		return status, nil
+	}
+	status.Done, err = getInt(row["statements_done"])
+	return
+}
+
+func getMigrated(migrated interface{}) (bool, error) {
+	switch value := migrated.(type) {
+	case int64:
+		return value == 1, nil
+	case int:
+		return value == 1, nil
+	case string:
+		parsed, err := strconv.ParseInt(value, 10, 8)
+		if err != nil {
+			return false, err
+		}
+		return parsed == 1, nil
+	}
+	return false, fmt.Errorf("migrated flag is not an integer")
+}
+
+func getTimestamp(timestamp interface{}) (time.Time, error) {
+	switch value := timestamp.(type) {
+	case time.Time:
+		return value, nil
+	case string:
+		if len(value) < 1 {
+			return time.Time{}, nil
+		}
+		return time.ParseInLocation(TIMESTAMP_LAYOUT, value, time.UTC)
+	}
+	return time.Time{}, fmt.Errorf("timestamp is not a string")
+}
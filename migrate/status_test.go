@@ -0,0 +1,70 @@
+package migrate
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestStatus(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	first, err := writeMigration(path, "create_gadgets_table", 1, TEST_GADGETS_TABLE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Make(db, path).MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	missing := createFaultyMigration(t, path)
+	second, err := writeMigration(path, "alter_gadgets_add_colour", 2, TEST_ALTER_GADGETS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := Make(db, path).Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string]MigrationStatus)
+	for _, status := range statuses {
+		byName[status.Name] = status
+	}
+	if status := byName[first]; !status.Migrated || !status.FileExists || status.BatchID < 1 || status.AppliedAt.IsZero() {
+		t.Errorf("expected '%s' to be migrated with a batch and applied time, got %+v", first, status)
+	}
+	if status := byName[second]; status.Recorded || status.Migrated || !status.Pending() {
+		t.Errorf("expected '%s' to be pending and unrecorded, got %+v", second, status)
+	}
+	if status := byName[missing]; !status.Recorded || status.FileExists || status.Pending() {
+		t.Errorf("expected '%s' to be recorded without a file, got %+v", missing, status)
+	}
+	if statuses[len(statuses)-1].Name != second {
+		t.Errorf("expected unrecorded migrations to be listed last")
+	}
+}
+
+func TestStatusWithoutTable(t *testing.T) {
+	reset()
+	statuses, err := Make(db, "/path/that/does/not/exist").Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) > 0 {
+		t.Errorf("expected no statuses, got %d", len(statuses))
+	}
+}
+
+// writeMigration writes a migration file with the given timestamp and returns its name
+func writeMigration(path, name string, timestamp int64, content string) (string, error) {
+	fullname := fmt.Sprintf("%s.%d", name, timestamp)
+	return fullname, writeFile(fmt.Sprintf("%s/%s.sql", path, fullname), content)
+}
+
+func cleanGadgets() {
+	if _, err := db.Exec("DROP TABLE IF EXISTS gadgets", nil); err != nil {
+		panic(err)
+	}
+	reset()
+}
@@ -0,0 +1,165 @@
+package migrate
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+)
+
+const (
+	// TABLE_VERSION is the version of the layout of the migrations table that
+	// the dialects create, and to which older tables are upgraded
+	TABLE_VERSION = 4
+
+	VERSION_TABLE_SUFFIX      = "_version"
+	VERSION_TABLE_PLACEHOLDER = "[version_table]"
+	VERSION_TABLE             = "CREATE TABLE [version_table] (version INT NOT NULL)"
+	VERSION_QUERY             = "SELECT version FROM [version_table]"
+	VERSION_DELETE            = "DELETE FROM [version_table]"
+	VERSION_INSERT            = "INSERT INTO [version_table] (version) VALUES (?)"
+	ADD_COLUMN                = "ALTER TABLE [table] ADD COLUMN %s %s"
+)
+
+// tableUpgrade upgrades the migrations table from the version before to its own
+type tableUpgrade struct {
+	version     int
+	description string
+	upgrade     func(m *Migration) error
+}
+
+// tableUpgrades are applied in order to migrations tables created by earlier
+// versions of the package. When the layout of the table changes, change the
+// dialects' CreateTable, add a step and bump TABLE_VERSION. Steps should be safe
+// to repeat, since DDL is not transactional everywhere.
+var tableUpgrades = []tableUpgrade{
+	{2, "add the checksum column", func(m *Migration) error {
+		return m.addColumn("checksum", "VARCHAR(64) NULL")
+	}},
+	{3, "add the columns that describe each run", func(m *Migration) error {
+		for _, column := range [][2]string{
+			{"duration_ms", "BIGINT NULL"},
+			{"host", "VARCHAR(255) NULL"},
+			{"db_user", "VARCHAR(255) NULL"},
+			{"app_version", "VARCHAR(255) NULL"},
+			{"direction", "VARCHAR(4) NULL"},
+		} {
+			if err := m.addColumn(column[0], column[1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{4, "add the columns that track migrations that failed part of the way through", func(m *Migration) error {
+		if err := m.addColumn("dirty", "SMALLINT NULL"); err != nil {
+			return err
+		}
+		return m.addColumn("statements_done", "INT NULL")
+	}},
+}
+
+// upgradeTable brings a migrations table created by an earlier version of the
+// package up to TABLE_VERSION, recording the version as it goes
+func (m *Migration) upgradeTable() error {
+	version, recorded, err := m.tableVersion()
+	if err != nil {
+		return err
+	}
+	if version > TABLE_VERSION {
+		log.Printf("warning: the migrations table %s is at version %d, but this version of MySqlMigrate only knows up to version %d\n", m.table(), version, TABLE_VERSION)
+		return nil
+	}
+	for _, step := range tableUpgrades {
+		if step.version <= version {
+			continue
+		}
+		if err = step.upgrade(m); err != nil {
+			return fmt.Errorf("could not upgrade the migrations table to version %d (%s): %w", step.version, step.description, err)
+		}
+		if err = m.setTableVersion(step.version); err != nil {
+			return err
+		}
+		version, recorded = step.version, true
+	}
+	if recorded {
+		return nil
+	}
+	return m.setTableVersion(version)
+}
+
+// tableVersion returns the version of the migrations table and whether it has
+// been recorded. The layout of tables created before versions were recorded
+// gives their version.
+func (m *Migration) tableVersion() (version int, recorded bool, err error) {
+	hasTable, err := m.tableExists(m.tableName + VERSION_TABLE_SUFFIX)
+	if err != nil {
+		return
+	}
+	if hasTable {
+		rows, err := m.query(VERSION_QUERY, nil)
+		if err != nil {
+			return 0, false, err
+		}
+		if len(rows) > 0 {
+			version, err = getInt(rows[0]["version"])
+			return version, true, err
+		}
+	}
+	hasChecksum, err := m.hasColumn("checksum")
+	if err != nil || !hasChecksum {
+		return 1, false, err
+	}
+	return 2, false, nil
+}
+
+// setTableVersion records the version of the migrations table, creating the
+// table that holds it if need be
+func (m *Migration) setTableVersion(version int) error {
+	hasTable, err := m.tableExists(m.tableName + VERSION_TABLE_SUFFIX)
+	if err != nil {
+		return err
+	}
+	if !hasTable {
+		if _, err = m.exec(VERSION_TABLE, nil); err != nil {
+			return err
+		}
+	}
+	if _, err = m.exec(VERSION_DELETE, nil); err != nil {
+		return err
+	}
+	_, err = m.exec(VERSION_INSERT, []interface{}{version})
+	return err
+}
+
+// hasColumn reports whether the migrations table has the column
+func (m *Migration) hasColumn(column string) (bool, error) {
+	rows, err := m.query(m.dialect.ColumnQuery(), []interface{}{m.tableSchema, m.tableName, column})
+	if err != nil || len(rows) < 1 {
+		return false, err
+	}
+	total, _ := rows[0]["total"].(int64)
+	return total > 0, nil
+}
+
+// addColumn adds the column to the migrations table unless it already has it
+func (m *Migration) addColumn(column, definition string) error {
+	hasColumn, err := m.hasColumn(column)
+	if err != nil || hasColumn {
+		return err
+	}
+	_, err = m.exec(fmt.Sprintf(ADD_COLUMN, column, definition), nil)
+	return err
+}
+
+func getInt(integer interface{}) (int, error) {
+	switch value := integer.(type) {
+	case int64:
+		return int(value), nil
+	case int:
+		return value, nil
+	case string:
+		return strconv.Atoi(value)
+	case []byte:
+		return strconv.Atoi(string(value))
+	}
+	return 0, fmt.Errorf("%v is not an integer", integer)
+}
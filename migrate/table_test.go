@@ -0,0 +1,123 @@
+package migrate
+
+import (
+	"testing"
+)
+
+const (
+	TEST_TABLE_SCHEMA = "test_mysql_mig_tables"
+	TEST_TABLE_QUERY  = "SELECT COUNT(*) AS total FROM information_schema.tables WHERE table_schema = COALESCE(?, DATABASE()) AND table_name = ?"
+)
+
+func TestTable(t *testing.T) {
+	conn := getConnection(t)
+	defer conn.Close()
+	getSchemaConnection(t, TEST_TABLE_SCHEMA)
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	defer db.Exec("DROP TABLE IF EXISTS schema_migrations, schema_migrations_version", nil)
+	names := seedGadgets(t, path)
+	for _, options := range [][]Option{
+		{WithTable("schema_migrations")},
+		{WithTable("migration_log"), WithTableSchema(TEST_TABLE_SCHEMA)},
+	} {
+		options = append(options, WithConnection(conn))
+		if _, err = Make(db, path, options...).MigrateUp(); err != nil {
+			t.Fatal(err)
+		}
+		m := Make(db, path, options...)
+		checkTable(t, m)
+		statuses, err := m.Status()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(statuses) != len(names) {
+			t.Errorf("expected %d migrations in '%s', got %d", len(names), m.table(), len(statuses))
+		}
+		for _, status := range statuses {
+			if !status.Migrated {
+				t.Errorf("expected '%s' to have been recorded as migrated in '%s'", status.Name, m.table())
+			}
+		}
+		if drifts, err := m.Verify(); err != nil || len(drifts) > 0 {
+			t.Errorf("expected no drift, got %v (%v)", drifts, err)
+		}
+		if _, err = m.MigrateDown(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if exists, err := db.CheckHasTable("migrations"); err != nil || exists {
+		t.Errorf("expected no migrations table to have been created (%v)", err)
+	}
+}
+
+func TestTableUpgrade(t *testing.T) {
+	reset()
+	defer reset()
+	if _, err := db.Exec(LEGACY_MIGS_TABLE, nil); err != nil {
+		t.Fatal(err)
+	}
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := Make(db, path)
+	if _, err = m.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	checkTableVersion(t, m, TABLE_VERSION)
+	if hasColumn, err := m.hasColumn("checksum"); err != nil || !hasColumn {
+		t.Errorf("expected the checksum column to have been added (%v)", err)
+	}
+
+	// Upgrades that have already been applied are skipped
+	if _, err = m.exec(VERSION_DELETE, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.exec(VERSION_INSERT, []interface{}{1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	checkTableVersion(t, m, TABLE_VERSION)
+
+	// Tables from a later version are left alone
+	if _, err = m.exec(VERSION_DELETE, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.exec(VERSION_INSERT, []interface{}{TABLE_VERSION + 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	checkTableVersion(t, m, TABLE_VERSION+1)
+}
+
+func checkTableVersion(t *testing.T, m *Migration, expected int) {
+	version, recorded, err := m.tableVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !recorded || version != expected {
+		t.Errorf("expected version %d of the migrations table to have been recorded, got %d (recorded: %t)", expected, version, recorded)
+	}
+}
+
+func checkTable(t *testing.T, m *Migration) {
+	var schema interface{}
+	if len(m.tableSchema) > 0 {
+		schema = m.tableSchema
+	}
+	rows, err := db.QueryRaw(TEST_TABLE_QUERY, []interface{}{schema, m.tableName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total, _ := rows[0]["total"].(int64); total != 1 {
+		t.Errorf("expected the table '%s' to have been created", m.table())
+	}
+}
@@ -0,0 +1,182 @@
+package migrate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MigrateTo runs and reverses migrations so that the target, and every migration
+// before it, has been run and every migration after it has been reversed. The
+// target is the name of a migration (with or without its timestamp) or its
+// migration id. Later migrations are reversed newest first before any earlier
+// pending migrations are run, oldest first, as a new batch.
+func (m *Migration) MigrateTo(target string) (result Result, err error) {
+	result.DryRun = m.dryRun
+	err = m.withLock(func() error {
+		if result.Migrations, err = m.migrateTo(target); err != nil {
+			return err
+		}
+		return m.writeSchema()
+	})
+	return
+}
+
+func (m *Migration) migrateTo(target string) ([]MigrationResult, error) {
+	if err := m.prepare(); err != nil {
+		return nil, err
+	}
+	if err := m.checkDirty(); err != nil {
+		return nil, err
+	}
+	if err := m.handleMissing(); err != nil {
+		return nil, err
+	}
+	rows, err := m.records()
+	if err != nil {
+		return nil, err
+	}
+	index, err := targetIndex(rows, target)
+	if err != nil {
+		return nil, err
+	}
+	down, up, err := splitAtTarget(rows, index)
+	if err != nil {
+		return nil, err
+	}
+	if len(down) < 1 && len(up) < 1 {
+		return nil, nil
+	}
+	if len(up) > 0 {
+		if err = m.checkDrift(); err != nil {
+			return nil, err
+		}
+	}
+	steps := []struct {
+		direction  bool
+		candidates []map[string]interface{}
+		migrations map[int]*migrationFile
+	}{{direction: false, candidates: down}, {direction: true, candidates: up}}
+	// Both steps are loaded before either is run, so that nothing is run if
+	// the migrations of either cannot be read
+	for i := range steps {
+		m.direction = steps[i].direction
+		m.migrationCandidates = steps[i].candidates
+		if err = m.loadCandidates(); err != nil {
+			return nil, err
+		}
+		steps[i].migrations = m.migrations
+	}
+	results := make([]MigrationResult, 0)
+	for _, step := range steps {
+		if len(step.migrations) < 1 {
+			continue
+		}
+		m.direction = step.direction
+		m.migrations = step.migrations
+		ran, err := m.runMigrations()
+		results = append(results, ran...)
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// records lists every recorded migration, including those that would have been
+// recorded were this not a dry run, ordered by migration id
+func (m *Migration) records() ([]map[string]interface{}, error) {
+	rows := make([]map[string]interface{}, 0)
+	if m.hasTable {
+		var err error
+		if rows, err = m.query(STATUS_QUERY, nil); err != nil {
+			return nil, err
+		}
+	}
+	if len(m.seeded) < 1 {
+		return rows, nil
+	}
+	rows = append(rows, m.seeded...)
+	for _, row := range rows {
+		if _, _, err := getNameAndID(row); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		_, first, _ := getNameAndID(rows[i])
+		_, second, _ := getNameAndID(rows[j])
+		return first < second
+	})
+	return rows, nil
+}
+
+// presentRecords lists the recorded migrations that are in the source, or are
+// registered in Go, ordered by migration id
+func (m *Migration) presentRecords() ([]map[string]interface{}, error) {
+	rows, err := m.records()
+	if err != nil {
+		return nil, err
+	}
+	inSource := make(map[string]bool)
+	for _, name := range m.files {
+		inSource[name] = true
+	}
+	present := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		if name, _, err := getNameAndID(row); err == nil && inSource[name] {
+			present = append(present, row)
+		}
+	}
+	return present, nil
+}
+
+// targetIndex finds the position of the target in the rows, which are ordered by
+// migration id
+func targetIndex(rows []map[string]interface{}, target string) (int, error) {
+	id, idErr := strconv.ParseInt(target, 10, 64)
+	matches := make([]int, 0)
+	for i, row := range rows {
+		name, migrationID, err := getNameAndID(row)
+		if err != nil {
+			return 0, err
+		}
+		if name == target || (idErr == nil && migrationID == id) {
+			return i, nil
+		}
+		if strings.Split(name, ".")[0] == target {
+			matches = append(matches, i)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	if len(matches) > 1 {
+		return 0, fmt.Errorf("more than one migration is named '%s'; include the timestamp or use the migration id", target)
+	}
+	return 0, fmt.Errorf("migration '%s' not found", target)
+}
+
+// splitAtTarget returns the migrated rows after the index and the rows up to and
+// including the index that have yet to be migrated. Migrations recorded by
+// Baseline were never run, so reversing one is refused.
+func splitAtTarget(rows []map[string]interface{}, index int) (down, up []map[string]interface{}, err error) {
+	down = make([]map[string]interface{}, 0)
+	up = make([]map[string]interface{}, 0)
+	for i, row := range rows {
+		migrated, err := getMigrated(row["migrated"])
+		if err != nil {
+			return nil, nil, err
+		}
+		if i > index && migrated {
+			if batch, _ := getBatchID(row["batch_id"]); batch == BASELINE_BATCH {
+				name, _, _ := getNameAndID(row)
+				return nil, nil, fmt.Errorf("cannot reverse '%s', which was recorded by Baseline without being run; record it as not run with Force instead", name)
+			}
+			down = append(down, row)
+		} else if i <= index && !migrated {
+			up = append(up, row)
+		}
+	}
+	return down, up, nil
+}
@@ -0,0 +1,128 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMigrateTo(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	names := seedGadgets(t, path)
+	if _, err = Make(db, path).MigrateTo(names[1]); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, path, map[string]bool{names[0]: true, names[1]: true, names[2]: false})
+
+	// Back to the first migration using its id
+	if _, err = Make(db, path).MigrateTo("1"); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, path, map[string]bool{names[0]: true, names[1]: false, names[2]: false})
+
+	// Forward to the last migration using its name without the timestamp
+	if _, err = Make(db, path).MigrateTo("alter_gadgets_add_weight"); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, path, map[string]bool{names[0]: true, names[1]: true, names[2]: true})
+
+	if _, err = Make(db, path).MigrateTo("no_such_migration"); err == nil {
+		t.Errorf("expected an error for a target that does not exist")
+	}
+}
+
+func TestMigrateToPartial(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	names := make([]string, 0)
+	for _, mig := range []struct {
+		name      string
+		timestamp int64
+		content   string
+	}{
+		{"create_gadgets_table", 1, TEST_GADGETS_TABLE},
+		{"alter_gadgets_add_weight", 3, TEST_ALTER_GADGETS_AGAIN},
+	} {
+		name, err := writeMigration(path, mig.name, mig.timestamp, mig.content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if _, err = Make(db, path).MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	broken, err := writeMigration(path, "alter_missing_table", 2, strings.Replace(TEST_ALTER_GADGETS, "gadgets", "missing_table", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The later migration is reversed before the broken one fails
+	result, err := Make(db, path).MigrateTo(broken)
+	if err == nil {
+		t.Fatalf("expected '%s' to fail", broken)
+	}
+	if len(result.Migrations) != 1 || result.Migrations[0].Name != names[1] || result.Migrations[0].Direction != DIRECTION_DOWN {
+		t.Errorf("expected the result to include the reversal of '%s', got %+v", names[1], result.Migrations)
+	}
+	checkMigrated(t, path, map[string]bool{names[0]: true, broken: false, names[1]: false})
+}
+
+func seedGadgets(t *testing.T, path string) []string {
+	names := make([]string, 0)
+	for i, mig := range []struct{ name, content string }{
+		{"create_gadgets_table", TEST_GADGETS_TABLE},
+		{"alter_gadgets_add_colour", TEST_ALTER_GADGETS},
+		{"alter_gadgets_add_weight", TEST_ALTER_GADGETS_AGAIN},
+	} {
+		name, err := writeMigration(path, mig.name, int64(i+1), mig.content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func checkMigrated(t *testing.T, path string, expected map[string]bool) {
+	statuses, err := Make(db, path).Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if migrated, ok := expected[status.Name]; ok && migrated != status.Migrated {
+			t.Errorf("expected '%s' to have migrated = %t", status.Name, migrated)
+		}
+	}
+}
+
+func TestMigrateSteps(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	names := seedGadgets(t, path)
+	if _, err = Make(db, path).MigrateUpSteps(2); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, path, map[string]bool{names[0]: true, names[1]: true, names[2]: false})
+	if _, err = Make(db, path).MigrateUpSteps(1); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, path, map[string]bool{names[0]: true, names[1]: true, names[2]: true})
+
+	// Steps are counted across batches
+	if _, err = Make(db, path).MigrateDownSteps(2); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, path, map[string]bool{names[0]: true, names[1]: false, names[2]: false})
+	if _, err = Make(db, path).MigrateDownSteps(0); err == nil {
+		t.Errorf("expected an error for zero steps")
+	}
+}
@@ -67,5 +67,65 @@ const (
 -- add your DOWN SQL here
 
 [STATEMENT] ALTER TABLE widgets DROP COLUMN pricing_type;
+`
+
+	TEST_GADGETS_TABLE = `
+-- add your UP SQL here
+
+[STATEMENT] CREATE TABLE gadgets (
+	id INT(6) UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+	name VARCHAR(255) NOT NULL
+);
+
+-- [DIRECTION] -- do not alter this line!
+-- add your DOWN SQL here
+
+[STATEMENT] DROP TABLE gadgets;
+`
+
+	TEST_ALTER_GADGETS = `
+-- add your UP SQL here
+
+[STATEMENT] ALTER TABLE gadgets ADD colour VARCHAR(50) NULL;
+
+-- [DIRECTION] -- do not alter this line!
+-- add your DOWN SQL here
+
+[STATEMENT] ALTER TABLE gadgets DROP COLUMN colour;
+`
+
+	TEST_ALTER_GADGETS_AGAIN = `
+-- add your UP SQL here
+
+[STATEMENT] ALTER TABLE gadgets ADD weight INT NULL;
+
+-- [DIRECTION] -- do not alter this line!
+-- add your DOWN SQL here
+
+[STATEMENT] ALTER TABLE gadgets DROP COLUMN weight;
+`
+	TEST_FAILING_INSERT = `
+-- add your UP SQL here
+
+[STATEMENT] INSERT INTO gadgets (name) VALUES ('sprocket');
+[STATEMENT] INSERT INTO no_such_table (name) VALUES ('sprocket');
+
+-- [DIRECTION] -- do not alter this line!
+-- add your DOWN SQL here
+
+[STATEMENT] DELETE FROM gadgets WHERE name = 'sprocket';
+`
+
+	TEST_FAILING_INSERT_NO_TRANSACTION = `
+-- [NO TRANSACTION]
+-- add your UP SQL here
+
+[STATEMENT] INSERT INTO gadgets (name) VALUES ('sprocket');
+[STATEMENT] INSERT INTO no_such_table (name) VALUES ('sprocket');
+
+-- [DIRECTION] -- do not alter this line!
+-- add your DOWN SQL here
+
+[STATEMENT] DELETE FROM gadgets WHERE name = 'sprocket';
 `
 )
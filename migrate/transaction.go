@@ -0,0 +1,133 @@
+package migrate
+
+import (
+	"log"
+	"strings"
+)
+
+const (
+	// NO_TRANSACTION opts a migration file out of being run in a transaction
+	NO_TRANSACTION = "[NO TRANSACTION]"
+)
+
+// Statements that cause MySQL to commit the current transaction implicitly, keyed
+// by their first keyword. TEMPORARY tables are the exception for CREATE and DROP.
+var implicitCommits = map[string]bool{
+	"ALTER":     true,
+	"ANALYZE":   true,
+	"BEGIN":     true,
+	"CACHE":     true,
+	"CHECK":     true,
+	"CREATE":    true,
+	"DROP":      true,
+	"FLUSH":     true,
+	"GRANT":     true,
+	"INSTALL":   true,
+	"LOCK":      true,
+	"OPTIMIZE":  true,
+	"RENAME":    true,
+	"REPAIR":    true,
+	"RESET":     true,
+	"REVOKE":    true,
+	"START":     true,
+	"TRUNCATE":  true,
+	"UNINSTALL": true,
+	"UNLOCK":    true,
+}
+
+// useTransaction reports whether the migration can be wrapped in a transaction,
+// which requires a connection (see WithConnection) and no [NO TRANSACTION]
+// directive. A warning is logged when there is no connection.
+func (m *Migration) useTransaction(file *migrationFile) bool {
+	if strings.Contains(file.up+file.down, NO_TRANSACTION) {
+		return false
+	}
+	if m.conn == nil {
+		log.Printf("warning: migration '%s' is being run without a transaction; supply the connection pool with WithConnection\n", file.name)
+		return false
+	}
+	return m.conn.transactional()
+}
+
+// executeInTransaction runs the statements of the migration and updates its record
+// in a single transaction, which is rolled back if anything fails. On MySQL, a
+// statement that commits implicitly also commits the statements before it, even
+// if it fails, so a migration that mixes DML with a later DDL statement is not
+// atomic; it is left dirty instead (see progress).
+func (m *Migration) executeInTransaction(file *migrationFile, properties map[string]interface{}, result *MigrationResult, skip int) (err error) {
+	if keyword, ok := m.dialect.ImplicitCommit(getStatements(m.dialect, file.sql)); ok && !file.isFunc {
+		log.Printf("warning: migration '%s' contains a statement that commits implicitly (%s); it cannot be run atomically", file.name, keyword)
+	}
+	return m.withTransaction(func(exec Executor) error {
+		if err := m.runTimed(file, exec, properties, result, skip, true); err != nil {
+			return err
+		}
+		return exec.UpdateRecord(m.table(), "migration_id", properties)
+	})
+}
+
+// withTransaction runs fn in a transaction, which is committed if fn succeeds and
+// rolled back otherwise. Without a connection (see WithConnection), or within a
+// transaction, fn is run with the database as it is.
+func (m *Migration) withTransaction(fn func(exec Executor) error) (err error) {
+	if m.conn == nil || !m.conn.transactional() {
+		return fn(m.database)
+	}
+	tx, err := m.conn.begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	if err = fn(&sqlExecutor{runner: tx, dialect: m.dialect}); err != nil {
+		return
+	}
+	return tx.Commit()
+}
+
+// findImplicitCommit returns the keyword of the first statement that would commit
+// a transaction implicitly
+func findImplicitCommit(statements []string) (string, bool) {
+	for _, statement := range statements {
+		words := strings.Fields(strings.ToUpper(stripComments(statement)))
+		if len(words) < 1 {
+			continue
+		}
+		if (words[0] == "CREATE" || words[0] == "DROP") && len(words) > 1 && words[1] == "TEMPORARY" {
+			continue
+		}
+		if implicitCommits[words[0]] {
+			return words[0], true
+		}
+		if words[0] == "SET" && len(words) > 1 && words[1] == "PASSWORD" {
+			return "SET PASSWORD", true
+		}
+	}
+	return "", false
+}
+
+// stripComments removes the comments that precede a statement
+func stripComments(statement string) string {
+	for {
+		statement = strings.TrimSpace(statement)
+		switch {
+		case strings.HasPrefix(statement, "--"), strings.HasPrefix(statement, "#"):
+			end := strings.Index(statement, "\n")
+			if end < 0 {
+				return ""
+			}
+			statement = statement[end+1:]
+		case strings.HasPrefix(statement, "/*"):
+			end := strings.Index(statement, "*/")
+			if end < 0 {
+				return ""
+			}
+			statement = statement[end+2:]
+		default:
+			return statement
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package migrate
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTransactionRollback(t *testing.T) {
+	conn := getConnection(t)
+	defer conn.Close()
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	created, err := writeMigration(path, "create_gadgets_table", 1, TEST_GADGETS_TABLE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failing, err := writeMigration(path, "insert_gadgets", 2, TEST_FAILING_INSERT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Make(db, path, WithConnection(conn)).MigrateUp(); err == nil {
+		t.Fatalf("expected the failing migration to return an error")
+	}
+	checkMigrated(t, path, map[string]bool{created: true, failing: false})
+	if count := countGadgets(t); count != 0 {
+		t.Errorf("expected the insert to have been rolled back, found %d gadgets", count)
+	}
+
+	// Without a transaction the first insert is kept
+	if err = writeFile(fmt.Sprintf("%s/%s.sql", path, failing), TEST_FAILING_INSERT_NO_TRANSACTION); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Make(db, path, WithConnection(conn)).MigrateUp(); err == nil {
+		t.Fatalf("expected the failing migration to return an error")
+	}
+	if count := countGadgets(t); count != 1 {
+		t.Errorf("expected the insert to have been kept without a transaction, found %d gadgets", count)
+	}
+}
+
+func TestTransactionWarning(t *testing.T) {
+	path, err := initTestDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanGadgets()
+	name, err := writeMigration(path, "create_gadgets_table", 1, TEST_GADGETS_TABLE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := captureLog(t, func() error {
+		_, err := Make(db, path).MigrateUp()
+		return err
+	})
+	if !strings.Contains(output, fmt.Sprintf("warning: migration '%s' is being run without a transaction", name)) {
+		t.Errorf("expected a warning without a connection, got '%s'", output)
+	}
+}
+
+func TestFindImplicitCommit(t *testing.T) {
+	cases := map[string]bool{
+		"-- a comment\nCREATE TABLE t (id INT)":      true,
+		"/* a comment */ alter table t add x int":    true,
+		"CREATE TEMPORARY TABLE t (id INT)":          false,
+		"# a comment\nINSERT INTO t (id) VALUES (1)": false,
+		"SET PASSWORD FOR 'user' = 'secret'":         true,
+		"UPDATE t SET id = 2":                        false,
+	}
+	for statement, expected := range cases {
+		if _, ok := findImplicitCommit([]string{statement}); ok != expected {
+			t.Errorf("expected implicit commit to be %t for %q", expected, statement)
+		}
+	}
+}
+
+func getConnection(t *testing.T) *sql.DB {
+	conn, err := sql.Open("mysql", fmt.Sprintf("root:%s@tcp(127.0.0.1:%s)/%s", ts.Password(), ts.HostPortStr(), TEST_DB_NAME))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func countGadgets(t *testing.T) int64 {
+	rows, err := db.QueryRaw("SELECT COUNT(*) AS total FROM gadgets", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total, _ := rows[0]["total"].(int64)
+	return total
+}